@@ -0,0 +1,480 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/inkyblackness/imgui-go/v3"
+	"github.com/solarlune/masterplan/model"
+)
+
+// The folder next to a Project's file that files pasted into or collected for the Project are kept in. Projects
+// saved in the same folder share it (see UnusedAssets()).
+const ASSETS_DIRECTORY = "assets"
+
+// Where files pasted into a Project that hasn't been saved yet go, under the user's cache directory, until it's saved
+// and they can be moved into its assets folder.
+const PASTED_ASSETS_PATH = "MasterPlan/pasted"
+
+// AssetsPath returns the path to the Project's assets folder, or an empty string if the Project hasn't been saved
+// (and so doesn't have anywhere to put one).
+func (project *Project) AssetsPath() string {
+	if project.FilePath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(project.FilePath), ASSETS_DIRECTORY)
+}
+
+// PastePath returns the folder files pasted into the Project should be saved to: the assets folder, or a holding
+// folder in the cache if the Project hasn't been saved.
+func (project *Project) PastePath() string {
+	if assets := project.AssetsPath(); assets != "" {
+		return assets
+	}
+	return filepath.Join(xdg.CacheHome, PASTED_ASSETS_PATH)
+}
+
+// IsAsset returns if the file path is within the Project's assets folder.
+func (project *Project) IsAsset(filePath string) bool {
+	return project.AssetsPath() != "" && isWithinDirectory(filePath, project.AssetsPath())
+}
+
+func isWithinDirectory(filePath, directory string) bool {
+
+	absFile, err := filepath.Abs(filePath)
+	if err != nil {
+		return false
+	}
+
+	absDir, err := filepath.Abs(directory)
+	if err != nil {
+		return false
+	}
+
+	relative, err := filepath.Rel(absDir, absFile)
+	return err == nil && relative != "." && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+
+}
+
+// ImportAsset copies the file into the Project's assets folder under the name given, returning the path to the copy
+// (see copyIntoDirectory()).
+func (project *Project) ImportAsset(sourcePath, name string) (string, error) {
+
+	if project.AssetsPath() == "" {
+		return "", fmt.Errorf("the project has to be saved before it can have assets")
+	}
+
+	return copyIntoDirectory(sourcePath, project.AssetsPath(), name)
+
+}
+
+// copyIntoDirectory copies the file into the directory under the name given, returning the path to the copy. If
+// there's already a file by that name, the same file is used if it has the same contents, or the copy's given another
+// name if it doesn't.
+func copyIntoDirectory(sourcePath, directory, name string) (string, error) {
+
+	data, err := ioutil.ReadFile(sourcePath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return "", err
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	destination := filepath.Join(directory, name)
+
+	for i := 2; FileExists(destination); i++ {
+
+		if existing, err := ioutil.ReadFile(destination); err == nil && bytes.Equal(existing, data) {
+			return destination, nil
+		}
+
+		destination = filepath.Join(directory, fmt.Sprintf("%s (%d)%s", base, i, ext))
+
+	}
+
+	if err := WriteFileAtomically(destination, data); err != nil {
+		return "", err
+	}
+
+	return destination, nil
+
+}
+
+// ImportTaskAsset copies the file the Task uses into the Project's assets folder, and points the Task at the copy.
+// Remote files are copied from where they were downloaded to, so they have to have finished loading first.
+func (project *Project) ImportTaskAsset(task *Task) error {
+
+	if !task.UsesMedia() || task.FilePath == "" || project.IsAsset(task.FilePath) {
+		return nil
+	}
+
+	sourcePath := task.FilePath
+	name := filepath.Base(task.FilePath)
+
+	if model.IsRemotePath(task.FilePath) {
+
+		res := project.RetrieveResource(task.FilePath)
+
+		if res == nil || res.State != RESOURCE_STATE_LOADED {
+			return fmt.Errorf("[%s] hasn't been downloaded", task.FilePath)
+		}
+
+		sourcePath = res.LocalFilepath
+		name = downloadedFileName(task.FilePath, sourcePath)
+
+	}
+
+	assetPath, err := project.ImportAsset(sourcePath, name)
+	if err != nil {
+		return err
+	}
+
+	task.FilePath = assetPath
+	task.LoadResource()
+	project.UndoHistory.Capture(task)
+	project.MarkModified()
+
+	return nil
+
+}
+
+// downloadedFileName returns a name for the file downloaded from the URL to the local path given: the file name the
+// URL ends in, if it does. URLs don't have to end in a file name (or have an extension at all), so we fall back to
+// the downloaded file's name, or its type's extension.
+func downloadedFileName(resourcePath, localFilepath string) string {
+
+	name := ""
+
+	if parsed, err := url.Parse(resourcePath); err == nil {
+		name = path.Base(parsed.Path)
+	}
+
+	if name == "" || name == "/" || name == "." {
+		return filepath.Base(localFilepath)
+	}
+
+	if filepath.Ext(name) == "" {
+		if fileType, err := mimetype.DetectFile(localFilepath); err == nil {
+			name += fileType.Extension()
+		}
+	}
+
+	return name
+
+}
+
+// CollectResources copies every file the Project's Tasks use from outside of the assets folder into it, so the
+// Project can be moved (or shared) along with it without any of them going missing.
+func (project *Project) CollectResources() {
+
+	if project.AssetsPath() == "" {
+		project.Log("Save the project before collecting its resources.")
+		return
+	}
+
+	collected := 0
+
+	for _, task := range project.GetAllTasks() {
+
+		if !task.UsesMedia() || task.FilePath == "" || project.IsAsset(task.FilePath) {
+			continue
+		}
+
+		if err := project.ImportTaskAsset(task); err != nil {
+			project.Log("Could not collect [%s]: %s", task.FilePath, err.Error())
+		} else {
+			collected++
+		}
+
+	}
+
+	project.Log("Collected %d resources into [%s].", collected, project.AssetsPath())
+
+}
+
+// importTemporaryAssets moves files added to the Project before it had an assets folder (pasted into it before it
+// was saved, or extracted from the bundle it was opened from) into its assets folder.
+func (project *Project) importTemporaryAssets() {
+
+	temporaryPaths := []string{
+		filepath.Join(xdg.CacheHome, PASTED_ASSETS_PATH),
+		filepath.Join(xdg.CacheHome, BUNDLES_PATH),
+	}
+
+	for _, task := range project.GetAllTasks() {
+
+		if !task.UsesMedia() || task.FilePath == "" {
+			continue
+		}
+
+		for _, temporaryPath := range temporaryPaths {
+
+			if !isWithinDirectory(task.FilePath, temporaryPath) {
+				continue
+			}
+
+			temporaryFile := task.FilePath
+
+			if err := project.ImportTaskAsset(task); err != nil {
+				project.Log("Could not move [%s] into the assets folder: %s", temporaryFile, err.Error())
+			}
+
+			break
+
+		}
+
+	}
+
+}
+
+// UnusedAssets returns the files in the Project's assets folder that none of its Tasks use, including the Tasks that
+// could be brought back by undoing or redoing. The assets folder's shared by every project file next to the
+// Project's, including its backups, so the files they use count, too; if one of them can't be read, there's no
+// telling which files are unused, and an error's returned.
+func (project *Project) UnusedAssets() ([]string, error) {
+
+	unused := []string{}
+
+	assets := project.AssetsPath()
+
+	if assets == "" {
+		return unused, nil
+	}
+
+	used := map[string]bool{}
+
+	filePaths, err := project.neighbourFilePaths()
+	if err != nil {
+		return unused, err
+	}
+
+	filePaths = append(filePaths, project.UndoHistory.FilePaths()...)
+
+	for _, task := range project.GetAllTasks() {
+		if task.UsesMedia() && task.FilePath != "" {
+			filePaths = append(filePaths, task.FilePath)
+		}
+	}
+
+	for _, filePath := range filePaths {
+		if abs, err := filepath.Abs(filePath); err == nil {
+			used[abs] = true
+		}
+	}
+
+	filepath.Walk(assets, func(filePath string, info os.FileInfo, err error) error {
+
+		if err != nil || info.IsDir() {
+			return nil
+		}
+
+		if abs, err := filepath.Abs(filePath); err == nil && !used[abs] {
+			unused = append(unused, filePath)
+		}
+
+		return nil
+
+	})
+
+	return unused, nil
+
+}
+
+// neighbourFilePaths returns the files used by the other project files next to the Project's: other Projects, and
+// the Project's backups.
+func (project *Project) neighbourFilePaths() ([]string, error) {
+
+	filePaths := []string{}
+
+	dir := filepath.Dir(project.FilePath)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return filePaths, err
+	}
+
+	projectPath, _ := filepath.Abs(project.FilePath)
+
+	for _, file := range files {
+
+		ext := filepath.Ext(file.Name())
+
+		// Backups keep the extension of the project they're of.
+		if file.IsDir() || (ext != ".plan" && ext != filepath.Ext(project.FilePath)) {
+			continue
+		}
+
+		planPath := filepath.Join(dir, file.Name())
+
+		if abs, _ := filepath.Abs(planPath); abs == projectPath {
+			continue // The Project's own Tasks are counted as they are now
+		}
+
+		fileData, err := ioutil.ReadFile(planPath)
+		if err != nil {
+			return filePaths, err
+		}
+
+		document, err := model.ParseDocument(string(fileData), planPath)
+		if err != nil {
+			return filePaths, fmt.Errorf("could not read [%s]: %s", planPath, err.Error())
+		}
+
+		for _, task := range document.Tasks {
+			if task.UsesMedia() && task.FilePath != "" && !model.IsRemotePath(task.FilePath) {
+				filePaths = append(filePaths, task.FilePath)
+			}
+		}
+
+	}
+
+	return filePaths, nil
+
+}
+
+// DrawAssetsWindow draws the report of the files in the assets folder that aren't used by any Task, so they can
+// be cleaned up.
+func (project *Project) DrawAssetsWindow() {
+
+	imgui.SetNextWindowSizeV(imgui.Vec2{X: 480, Y: 280}, imgui.ConditionFirstUseEver)
+
+	if imgui.BeginV("Unused Assets", &project.UnusedAssetsOpen, 0) {
+
+		if project.AssetsPath() == "" {
+			imgui.Text("Save the project to give it an assets folder.")
+			imgui.End()
+			return
+		}
+
+		// Walking the folder every frame would be a waste, so the list's only updated when asked.
+		if refresh := imgui.Button("Refresh"); refresh || project.UnusedAssetList == nil {
+			project.UnusedAssetList, project.UnusedAssetsError = project.UnusedAssets()
+		}
+
+		// Deleting can't be undone, so it's confirmed first.
+		var deleting []string
+
+		if len(project.UnusedAssetList) > 0 {
+
+			imgui.SameLine()
+
+			if imgui.Button("Delete All") {
+				deleting = append([]string{}, project.UnusedAssetList...)
+			}
+
+		}
+
+		imgui.Separator()
+
+		if project.UnusedAssetsError != nil {
+			imgui.Text("Could not tell which files in the assets folder are in use:")
+			imgui.Text(project.UnusedAssetsError.Error())
+		} else if len(project.UnusedAssetList) == 0 {
+			imgui.Text("Every file in the assets folder is in use.")
+		} else {
+
+			imgui.Text(fmt.Sprintf("%d files in [%s] aren't used by any Task.", len(project.UnusedAssetList), project.AssetsPath()))
+			imgui.Text("Files used by deleted Tasks that undoing could bring back are left out, as they're still in use.")
+			imgui.Text("So are files used by other projects in the same folder, and by this project's backups.")
+
+			imgui.Separator()
+
+			for _, asset := range project.UnusedAssetList {
+
+				imgui.PushID(asset)
+
+				if imgui.Button("Delete") {
+					deleting = []string{asset}
+				}
+
+				imgui.SameLine()
+
+				imgui.Text(project.assetName(asset))
+
+				imgui.PopID()
+
+			}
+
+		}
+
+		if deleting != nil {
+			project.DeleteAssetsPrompt = deleting
+			imgui.OpenPopup("Delete Assets")
+		}
+
+		project.drawDeleteAssetsPrompt()
+
+	}
+
+	imgui.End()
+
+}
+
+// assetName returns the asset's path within the assets folder.
+func (project *Project) assetName(asset string) string {
+	if relative, err := filepath.Rel(project.AssetsPath(), asset); err == nil {
+		return relative
+	}
+	return asset
+}
+
+func (project *Project) drawDeleteAssetsPrompt() {
+
+	if imgui.BeginPopupModalV("Delete Assets", nil, imgui.WindowFlagsAlwaysAutoResize) {
+
+		assets := project.DeleteAssetsPrompt
+
+		if len(assets) == 0 {
+			imgui.CloseCurrentPopup()
+		} else {
+
+			if len(assets) == 1 {
+				imgui.Text(fmt.Sprintf("Delete \"%s\"?", project.assetName(assets[0])))
+			} else {
+				imgui.Text(fmt.Sprintf("Delete %d unused files?", len(assets)))
+			}
+
+			imgui.Text("This can't be undone.")
+
+			if imgui.Button("Delete") {
+				for _, asset := range assets {
+					project.deleteAsset(asset)
+				}
+				project.UnusedAssetList, project.UnusedAssetsError = project.UnusedAssets()
+				project.DeleteAssetsPrompt = nil
+				imgui.CloseCurrentPopup()
+			}
+
+			imgui.SameLine()
+
+			if imgui.Button("Cancel") {
+				project.DeleteAssetsPrompt = nil
+				imgui.CloseCurrentPopup()
+			}
+
+		}
+
+		imgui.EndPopup()
+	}
+
+}
+
+func (project *Project) deleteAsset(asset string) {
+	if err := os.Remove(asset); err != nil {
+		project.Log("Could not delete [%s]: %s", asset, err.Error())
+	} else {
+		project.Log("Deleted unused asset [%s].", asset)
+	}
+}
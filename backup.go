@@ -0,0 +1,170 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/inkyblackness/imgui-go/v3"
+)
+
+// The timestamp format used in backup file names; it sorts chronologically and doesn't use any
+// characters that are invalid in file names on Windows.
+const backupTimeFormat = "2006_01_02_15_04_05"
+
+type Backup struct {
+	Path string
+	Time time.Time
+}
+
+// BackupPath returns the path of a backup of the Project made at the given time, e.g. "plans/moodboard_bak_2020_10_12_17_30_00.plan".
+func (project *Project) BackupPath(t time.Time) string {
+	ext := filepath.Ext(project.FilePath)
+	return strings.TrimSuffix(project.FilePath, ext) + BackupDelineator + t.Format(backupTimeFormat) + ext
+}
+
+// parseBackupPath returns the path of the project file a backup was made of (see BackupPath()) and when it was made,
+// or false if the path isn't a backup's. Only the file's name is looked at, so folders that happen to have the
+// delineator in their names don't matter.
+func parseBackupPath(backupPath string) (string, time.Time, bool) {
+
+	dir, name := filepath.Split(backupPath)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	delineatorStart := len(stem) - len(backupTimeFormat) - len(BackupDelineator)
+
+	if delineatorStart <= 0 || stem[delineatorStart:delineatorStart+len(BackupDelineator)] != BackupDelineator {
+		return "", time.Time{}, false
+	}
+
+	backupTime, err := time.ParseInLocation(backupTimeFormat, stem[delineatorStart+len(BackupDelineator):], time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+
+	return dir + stem[:delineatorStart] + ext, backupTime, true
+
+}
+
+// Backups returns the backups that exist next to the Project's file, newest first.
+func (project *Project) Backups() []Backup {
+
+	backups := []Backup{}
+
+	if project.FilePath == "" {
+		return backups
+	}
+
+	dir, name := filepath.Split(project.FilePath)
+
+	if dir == "" {
+		dir = "."
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		project.Log("Could not list backups: %s", err.Error())
+		return backups
+	}
+
+	for _, file := range files {
+
+		if file.IsDir() {
+			continue
+		}
+
+		original, backupTime, isBackup := parseBackupPath(file.Name())
+		if !isBackup || original != name {
+			continue // Not one of ours
+		}
+
+		backups = append(backups, Backup{Path: filepath.Join(dir, file.Name()), Time: backupTime})
+
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+
+	return backups
+
+}
+
+// PruneBackups deletes the oldest backups past the number the program settings say to keep.
+func (project *Project) PruneBackups() {
+
+	keepCount := programSettings.BackupKeepCount
+
+	if keepCount <= 0 {
+		return
+	}
+
+	backups := project.Backups()
+
+	for i := keepCount; i < len(backups); i++ {
+		if err := os.Remove(backups[i].Path); err != nil {
+			project.Log("Could not remove old backup [ %s ]: %s", backups[i].Path, err.Error())
+		}
+	}
+
+}
+
+// HandleBackups saves a backup of the Project every time the backup interval elapses.
+func (project *Project) HandleBackups() {
+
+	interval := time.Duration(programSettings.BackupInterval) * time.Minute
+
+	if interval <= 0 || project.FilePath == "" {
+		return
+	}
+
+	if time.Since(project.LastBackup) >= interval {
+		project.LastBackup = time.Now()
+		project.Save(true)
+	}
+
+}
+
+func (project *Project) DrawBackupsWindow() {
+
+	imgui.SetNextWindowSizeV(imgui.Vec2{X: 360, Y: 240}, imgui.ConditionFirstUseEver)
+
+	if imgui.BeginV("Backups", &project.BackupsOpen, 0) {
+
+		backups := project.Backups()
+
+		if project.FilePath == "" {
+			imgui.Text("Save the project to start making backups.")
+		} else if len(backups) == 0 {
+			imgui.Text("There are no backups of this project yet.")
+		}
+
+		if imgui.Button("Back Up Now") {
+			project.Save(true)
+		}
+
+		imgui.Separator()
+
+		for _, backup := range backups {
+
+			imgui.PushID(backup.Path)
+
+			imgui.Text(backup.Time.Format("Jan 2 2006, 15:04:05"))
+			imgui.SameLine()
+
+			if imgui.Button("Restore") {
+				// Loading a backup loads it as the original project; it doesn't overwrite anything until it's saved.
+				project.ExecuteDestructiveAction(ActionLoadProject, backup.Path)
+			}
+
+			imgui.PopID()
+
+		}
+
+	}
+
+	imgui.End()
+
+}
@@ -5,25 +5,30 @@ import (
 	"io/ioutil"
 	"sort"
 	"strings"
-  "os/exec"
   "path/filepath"
 
 	"github.com/gabriel-vasile/mimetype"
 	rl "github.com/gen2brain/raylib-go/raylib"
 	uuid "github.com/gofrs/uuid"
+	"github.com/solarlune/masterplan/clipboard"
+	"github.com/solarlune/masterplan/model"
 )
 
-type Position struct {
-	X, Y int
-}
-
 type Board struct {
 	Tasks         []*Task
 	ToBeDeleted   []*Task
 	ToBeRestored  []*Task
 	Project       *Project
 	Name          string
-	TaskLocations map[Position][]*Task
+	TaskLocations *model.Grid // Where the Tasks are on the grid
+	Connections   []*Connection
+
+	// Whether Tasks have moved since the subtask hierarchy was last worked out
+	hierarchyChanged bool
+
+	// The Board's view while it's not the current Board; the current Board's view is the Project's CameraPan and Zoom.
+	Pan  rl.Vector2
+	Zoom float32
 }
 
 func NewBoard(project *Project) *Board {
@@ -31,7 +36,9 @@ func NewBoard(project *Project) *Board {
 		Tasks:         []*Task{},
 		Project:       project,
 		Name:          fmt.Sprintf("Board %d", len(project.Boards)+1),
-		TaskLocations: map[Position][]*Task{},
+		TaskLocations: model.NewGrid(),
+		Connections:   []*Connection{},
+		Zoom:          1,
 	}
 
 	return board
@@ -40,7 +47,8 @@ func NewBoard(project *Project) *Board {
 func (board *Board) CreateNewTask() *Task {
 	newTask := NewTask(board)
 	halfGrid := float32(board.Project.GridSize / 2)
-	gp := rl.Vector2{GetWorldMousePosition().X - halfGrid, GetWorldMousePosition().Y - halfGrid}
+	mousePos := rlVector(board.Project.Viewport.WorldMousePosition())
+	gp := rl.Vector2{mousePos.X - halfGrid, mousePos.Y - halfGrid}
 
 	newTask.Position = board.Project.LockPositionToGrid(gp)
 
@@ -51,6 +59,8 @@ func (board *Board) CreateNewTask() *Task {
 
 	newTask.TaskType = board.Project.PreviousTaskType
 
+	board.Project.UndoHistory.Capture(newTask)
+
 	if !board.Project.JustLoaded {
 		// If we're loading a project, we don't want to automatically select new tasks
 		board.Project.SendMessage(MessageSelect, map[string]interface{}{"task": newTask})
@@ -62,11 +72,13 @@ func (board *Board) CreateNewTask() *Task {
 func (board *Board) DeleteTask(task *Task) {
   board.ToBeDeleted = append(board.ToBeDeleted, task)
   task.ReceiveMessage(MessageDelete, map[string]interface{}{"task": task})
+  board.Project.UndoHistory.Capture(task)
 }
 
 func (board *Board) RestoreTask(task *Task) {
   board.ToBeRestored = append(board.ToBeRestored, task)
   task.ReceiveMessage(MessageDropped, map[string]interface{}{"task": task})
+  board.Project.UndoHistory.Capture(task)
 }
 
 func (board *Board) DeleteSelectedTasks() {
@@ -111,24 +123,37 @@ func (board *Board) FocusViewOnSelectedTasks() {
 
 func (board *Board) HandleDroppedFiles() {
 
-	if rl.IsFileDropped() {
+	if files := board.Project.Input.DroppedFiles(); len(files) > 0 {
 
-		fileCount := int32(0)
-		for _, filePath := range rl.GetDroppedFiles(&fileCount) {
+		for _, filePath := range files {
 
 			taskType, _ := mimetype.DetectFile(filePath)
 
+			// Images and sounds are copied into the assets folder first if the Project should keep its own copies.
+			if taskType != nil && programSettings.CopyResourcesToAssets && board.Project.AssetsPath() != "" {
+				if strings.Contains(taskType.String(), "image") || strings.Contains(taskType.String(), "audio") {
+					if assetPath, err := board.Project.ImportAsset(filePath, filepath.Base(filePath)); err == nil {
+						filePath = assetPath
+					} else {
+						board.Project.Log("Could not copy [%s] into the assets folder: %s", filePath, err.Error())
+					}
+				}
+			}
+
 			if taskType != nil {
 
 				task := NewTask(board)
-				task.Position.X = camera.Target.X
-				task.Position.Y = camera.Target.Y
+				task.Position = rlVector(board.Project.Viewport.Center())
 				success := true
 
 				if strings.Contains(taskType.String(), "image") {
 					task.TaskType = TASK_TYPE_IMAGE
 					task.FilePath = filePath
 					task.LoadResource()
+				} else if strings.Contains(taskType.String(), "audio") {
+					task.TaskType = TASK_TYPE_SOUND
+					task.FilePath = filePath
+					task.LoadResource()
 				} else if strings.HasPrefix(taskType.String(), "text/") {
 
 					// Attempt to read it in
@@ -144,13 +169,14 @@ func (board *Board) HandleDroppedFiles() {
 				}
 
 				board.Tasks = append(board.Tasks, task)
-				if !success {
+				if success {
+					board.Project.UndoHistory.Capture(task)
+				} else {
 					board.DeleteTask(task)
 				}
 				continue
 			}
 		}
-		rl.ClearDroppedFiles()
 
 	}
 
@@ -165,6 +191,9 @@ func (board *Board) CopySelectedTasks() {
 	for _, task := range board.SelectedTasks(false) {
 		board.Project.CopyBuffer = append(board.Project.CopyBuffer, task)
 	}
+
+	// They go on the system clipboard as well, so they can be pasted into other Projects or programs.
+	board.CopyTasksToClipboard(SystemClipboard(), board.Project.CopyBuffer)
 }
 
 func (board *Board) CutSelectedTasks() {
@@ -176,6 +205,13 @@ func (board *Board) CutSelectedTasks() {
 
 func (board *Board) PasteTasks() {
 
+	// Tasks on the clipboard that weren't copied from here were copied from another window since, so they're what's
+	// pasted; the copy buffer's only used for the Tasks copied here last.
+	if paste, err := clipboard.ReadPaste(SystemClipboard()); err == nil && paste.Kind == clipboard.PasteTasks && string(paste.Data) != board.Project.ClipboardTasks {
+		board.pasteClipboardTasks(paste)
+		return
+	}
+
 	if len(board.Project.CopyBuffer) > 0 {
 
 		for _, task := range board.Tasks {
@@ -191,28 +227,22 @@ func (board *Board) PasteTasks() {
 			srcTask.Board = ogBoard
 			board.Tasks = append(board.Tasks, clone)
 			clone.LoadResource()
+			board.Project.UndoHistory.Capture(clone)
 			clones = append(clones, clone)
 			return clone
 		}
 
-		center := rl.Vector2{}
+		bounds := []model.Rect{}
 
 		for _, t := range board.Project.CopyBuffer {
-			tp := t.Position
-			tp.X += t.Rect.Width / 2
-			tp.Y += t.Rect.Height / 2
-			center = rl.Vector2Add(center, tp)
+			bounds = append(bounds, model.Rect{t.Position.X, t.Position.Y, t.Rect.Width, t.Rect.Height})
 		}
 
-		center.X /= float32(len(board.Project.CopyBuffer))
-		center.Y /= float32(len(board.Project.CopyBuffer))
-
-		for _, srcTask := range board.Project.CopyBuffer {
+		positions := model.PastePositions(bounds, board.Project.Viewport.WorldMousePosition(), board.Project.GridSize)
 
-      clone := cloneTask(srcTask)
-      diff := rl.Vector2Subtract(GetWorldMousePosition(), center)
-      clone.Position = rl.Vector2Add(clone.Position, diff)
-      clone.Position = board.Project.LockPositionToGrid(clone.Position)
+		for i, srcTask := range board.Project.CopyBuffer {
+			clone := cloneTask(srcTask)
+			clone.Position = rlVector(positions[i])
 		}
 
 		board.ReorderTasks()
@@ -235,70 +265,51 @@ func (board *Board) PasteTasks() {
 
 }
 
+// PasteContent creates a Task from what's on the system clipboard: a note for text, or an image Task for an image
+// (which is saved next to the Project). Tasks copied from MasterPlan are pasted as Tasks.
 func (board *Board) PasteContent() {
+  board.PasteFromClipboard(SystemClipboard())
+}
+
+func (board *Board) PasteFromClipboard(clip clipboard.Clipboard) {
 
-  // TODO(justasd): :Portability
-  result, err := exec.Command("xclip", "-t", "TARGETS", "-o").CombinedOutput()
+  paste, err := clipboard.ReadPaste(clip)
   if err != nil {
-    board.Project.Log("Failed to get target data from xclip: '%s'.", err)
+    board.Project.Log("Failed to get the clipboard's contents: '%s'.", err)
     return
   }
 
-  result_str := string(result[:])
-  targets := strings.Split(strings.Replace(result_str, "\r\n", "\n", -1), "\n")
-
-  get_clipboard_data := func(target string) ([]byte, error) {
-    result, err := exec.Command("xclip", "-t", target, "-o").CombinedOutput()
-    if err != nil {
-      board.Project.Log("Failed to get clipboard data from xclip: '%s'.", err)
-      return nil, err
-    }
-
-    return result, nil
+  // Tasks copied from MasterPlan are pasted as they were.
+  if board.pasteClipboardTasks(paste) {
+    return
   }
 
-  //fmt.Println(targets)
+  switch paste.Kind {
 
-  for _, target := range targets {
-    if strings.EqualFold(target, "STRING") {
-      task := board.CreateNewTask()
-      task.TaskType = TASK_TYPE_NOTE
+  case clipboard.PasteText:
 
-      result, err := get_clipboard_data(target)
-      if err != nil { return }
+    task := board.CreateNewTask()
+    task.TaskType = TASK_TYPE_NOTE
+    task.Description = string(paste.Data)
 
-			task.Description = string(result[:])
+  case clipboard.PasteImage:
 
-      break
+    // The file's named for what the data actually is, rather than what the target says it is.
+    savePath, err := paste.SaveImage(board.Project.PastePath(), uuid.Must(uuid.NewV4()).String())
+    if err != nil {
+      board.Project.Log("Failed to save image file: '%s'.", err)
+      return
     }
 
-    if strings.Contains(target, "image") {
-
-      img_data , err := get_clipboard_data(target)
-      if err != nil { return }
-
-
-      id := uuid.Must(uuid.NewV4()).String()
-      dir := filepath.Dir(board.Project.FilePath)
-
-      // NOTE(justasd): Extension is a @HACK
-      save_path := filepath.Join(dir, id) + ".png"
-      err = ioutil.WriteFile(save_path, img_data, 0644)
-      if err != nil {
-        board.Project.Log("Failed to save iamge file to '%s'.", save_path)
-        return
-      }
+    board.Project.Log("Saved image to '%s'.", savePath)
 
-      fmt.Printf("Saved image to '%s'\n", save_path)
+    task := board.CreateNewTask()
+    task.TaskType = TASK_TYPE_IMAGE
+    task.FilePath = savePath
+    task.LoadResource()
 
-      task := board.CreateNewTask()
-      task.TaskType = TASK_TYPE_IMAGE
-      task.FilePath = save_path
-      task.LoadResource()
-
-      break
-    }
   }
+
 }
 
 func (board *Board) ReorderTasks() {
@@ -333,88 +344,30 @@ func (board *Board) Destroy() {
 }
 
 func (board *Board) GetTasksInPosition(x, y float32) []*Task {
-	cx, cy := board.Project.WorldToGrid(x, y)
-	return board.TaskLocations[Position{cx, cy}]
+	return gridTasks(board.TaskLocations.At(model.CellAt(x, y, board.Project.GridSize)))
 }
 
 func (board *Board) GetTasksInRect(x, y, w, h float32) []*Task {
+	return gridTasks(board.TaskLocations.InRect(model.Rect{x, y, w, h}, board.Project.GridSize))
+}
 
-	tasks := []*Task{}
-
-	added := func(t *Task) bool {
-		for _, t2 := range tasks {
-			if t2 == t {
-				return true
-			}
-		}
-		return false
-	}
-
-	for cy := y; cy < y+h; cy += float32(board.Project.GridSize) {
-
-		for cx := x; cx < x+w; cx += float32(board.Project.GridSize) {
-
-			for _, t := range board.GetTasksInPosition(cx, cy) {
-				if !added(t) {
-					tasks = append(tasks, t)
-				}
-			}
-
-		}
-
+// gridTasks returns the Tasks in the list of things on the grid.
+func gridTasks(items []interface{}) []*Task {
+	tasks := make([]*Task, 0, len(items))
+	for _, item := range items {
+		tasks = append(tasks, item.(*Task))
 	}
-
 	return tasks
 }
 
 func (board *Board) RemoveTaskFromGrid(task *Task) {
-
-	for _, position := range task.GridPositions {
-
-		for i, t := range board.TaskLocations[position] {
-
-			if t == task {
-				board.TaskLocations[position][i] = nil
-				board.TaskLocations[position] = append(board.TaskLocations[position][:i], board.TaskLocations[position][i+1:]...)
-				break
-			}
-
-		}
-
-	}
-
+	board.hierarchyChanged = true
+	board.TaskLocations.Remove(task)
 }
 
 func (board *Board) AddTaskToGrid(task *Task) {
-
-	positions := []Position{}
-
-	gs := float32(board.Project.GridSize)
-	startX, startY := int(task.Position.X/gs), int(task.Position.Y/gs)
-	endX, endY := int((task.Position.X+task.Rect.Width)/gs), int((task.Position.Y+task.Rect.Height)/gs)
-
-	for y := startY; y < endY; y++ {
-
-		for x := startX; x < endX; x++ {
-
-			p := Position{x, y}
-
-			positions = append(positions, p)
-
-			_, exists := board.TaskLocations[p]
-
-			if !exists {
-				board.TaskLocations[p] = []*Task{}
-			}
-
-			board.TaskLocations[p] = append(board.TaskLocations[p], task)
-
-		}
-
-	}
-
-	task.GridPositions = positions
-
+	board.hierarchyChanged = true
+	board.TaskLocations.Add(task, model.Rect{task.Position.X, task.Position.Y, task.Rect.Width, task.Rect.Height}, board.Project.GridSize)
 }
 
 func (board *Board) SelectedTasks(returnFirstSelectedTask bool) []*Task {
@@ -448,6 +401,7 @@ func (board *Board) HandleDeletedTasks() {
 			if task == t {
 				board.Tasks[index] = nil
 				board.Tasks = append(board.Tasks[:index], board.Tasks[index+1:]...)
+				board.removeConnectionsOf(task)
 				changed = true
 				break
 			}
@@ -459,6 +413,11 @@ func (board *Board) HandleDeletedTasks() {
 		board.Tasks = append(board.Tasks, task)
 		changed = true
 	}
+
+	// Connections come back once every restored Task is on the Board, as they can link restored Tasks together.
+	for _, task := range board.ToBeRestored {
+		board.restoreConnectionsOf(task)
+	}
 	board.ToBeRestored = []*Task{}
 
 	// We only want to reorder tasks if tasks were actually deleted or restored, as it is costly.
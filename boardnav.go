@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/inkyblackness/imgui-go/v3"
+)
+
+// The keybindings that switch directly to the first ten Boards, in order.
+var boardShortcuts = []string{KBBoard1, KBBoard2, KBBoard3, KBBoard4, KBBoard5, KBBoard6, KBBoard7, KBBoard8, KBBoard9, KBBoard10}
+
+// How many Board switches are kept to go back and forth through.
+const boardHistoryLength = 100
+
+// SwitchBoard makes the Board at the index the current Board, recording the switch in the navigation history.
+func (project *Project) SwitchBoard(index int) {
+
+	if index < 0 || index >= len(project.Boards) || index == project.BoardIndex {
+		return
+	}
+
+	if len(project.BoardHistory) == 0 {
+		project.BoardHistory = []*Board{project.CurrentBoard()}
+		project.BoardHistoryIndex = 0
+	}
+
+	// Going somewhere new drops whatever could have been gone forward to.
+	project.BoardHistory = append(project.BoardHistory[:project.BoardHistoryIndex+1], project.Boards[index])
+
+	if len(project.BoardHistory) > boardHistoryLength {
+		project.BoardHistory = project.BoardHistory[len(project.BoardHistory)-boardHistoryLength:]
+	}
+
+	project.BoardHistoryIndex = len(project.BoardHistory) - 1
+	project.BoardIndex = index
+
+	// The view's swapped right away so anything moving the camera after switching moves the new Board's view.
+	project.updateBoardView()
+
+}
+
+// SwitchBoardRelative switches to the Board the offset away from the current one in the Board list, wrapping around.
+func (project *Project) SwitchBoardRelative(offset int) {
+	count := len(project.Boards)
+	project.SwitchBoard(((project.BoardIndex+offset)%count + count) % count)
+}
+
+// NavigateBoardHistory goes back (for negative directions) or forward through the Boards that have been switched
+// between, skipping Boards that have since been deleted.
+func (project *Project) NavigateBoardHistory(direction int) {
+
+	for i := project.BoardHistoryIndex + direction; i >= 0 && i < len(project.BoardHistory); i += direction {
+
+		if index := project.BoardHistory[i].Index(); index >= 0 && index != project.BoardIndex {
+			project.BoardHistoryIndex = i
+			project.BoardIndex = index
+			project.updateBoardView()
+			return
+		}
+
+	}
+
+}
+
+// updateBoardView swaps the camera over to the current Board's view whenever the current Board changes, however it
+// changed, so each Board keeps its own pan and zoom.
+func (project *Project) updateBoardView() {
+
+	current := project.CurrentBoard()
+
+	if project.ViewBoard == current {
+		return
+	}
+
+	if project.ViewBoard != nil {
+		project.ViewBoard.Pan = project.CameraPan
+		project.ViewBoard.Zoom = project.Zoom
+	}
+
+	project.CameraPan = current.Pan
+	project.CameraOffset = current.Pan
+	project.Zoom = current.Zoom
+	project.CameraZoom = current.Zoom
+	project.ViewBoard = current
+
+}
+
+// OpenBoardPicker opens the quick switcher for picking a Board by name.
+func (project *Project) OpenBoardPicker() {
+	project.BoardPickerFilter = ""
+	project.OpenBoardPickerPrompt = true
+}
+
+func (project *Project) drawBoardPicker() {
+
+	if project.OpenBoardPickerPrompt {
+		imgui.OpenPopup("Switch Board")
+		project.OpenBoardPickerPrompt = false
+	}
+
+	imgui.SetNextWindowSizeV(imgui.Vec2{X: 320, Y: 0}, imgui.ConditionAlways)
+
+	if imgui.BeginPopupModalV("Switch Board", nil, imgui.WindowFlagsAlwaysAutoResize) {
+
+		if imgui.IsWindowAppearing() {
+			imgui.SetKeyboardFocusHere()
+		}
+
+		entered := imgui.InputTextV("##Filter", &project.BoardPickerFilter, imgui.InputTextFlagsEnterReturnsTrue, nil)
+
+		matches := project.MatchBoards(project.BoardPickerFilter)
+
+		picked := -1
+
+		if entered && len(matches) > 0 {
+			picked = matches[0].Index()
+		}
+
+		for i, board := range matches {
+			if imgui.SelectableV(fmt.Sprintf("%d. %s", board.Index()+1, board.Name), i == 0, 0, imgui.Vec2{}) {
+				picked = board.Index()
+			}
+		}
+
+		if len(matches) == 0 {
+			imgui.Text("No Boards match.")
+		}
+
+		if picked >= 0 || imgui.Button("Cancel") {
+			project.SwitchBoard(picked)
+			imgui.CloseCurrentPopup()
+		}
+
+		imgui.EndPopup()
+	}
+
+}
+
+// MatchBoards returns the Boards whose names fuzzily match the filter, best matches first. Every Board matches an
+// empty filter.
+func (project *Project) MatchBoards(filter string) []*Board {
+
+	type match struct {
+		Board *Board
+		Score int
+	}
+
+	matches := []match{}
+
+	for _, board := range project.Boards {
+		if score, ok := FuzzyMatch(filter, board.Name); ok {
+			matches = append(matches, match{board, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+
+	boards := []*Board{}
+	for _, m := range matches {
+		boards = append(boards, m.Board)
+	}
+
+	return boards
+
+}
+
+// FuzzyMatch returns whether all of the characters in the pattern appear in the text in order, ignoring case, and
+// a score for how good of a match it is; characters matched one after another or at the start of words score higher.
+func FuzzyMatch(pattern, text string) (int, bool) {
+
+	patternRunes := []rune(strings.ToLower(pattern))
+	textRunes := []rune(text)
+
+	score := 0
+	p := 0
+	previousMatch := -2
+
+	for i, c := range textRunes {
+
+		if p >= len(patternRunes) {
+			break
+		}
+
+		if unicode.ToLower(c) != patternRunes[p] {
+			continue
+		}
+
+		score++
+
+		if previousMatch == i-1 {
+			score += 2
+		}
+
+		if i == 0 || !unicode.IsLetter(textRunes[i-1]) && !unicode.IsDigit(textRunes[i-1]) || unicode.IsUpper(c) && unicode.IsLower(textRunes[i-1]) {
+			score += 3
+		}
+
+		previousMatch = i
+		p++
+
+	}
+
+	return score, p == len(patternRunes)
+
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/inkyblackness/imgui-go/v3"
+)
+
+// The imgui drag and drop payload type for reordering Boards in the Board panel; the payload is the Board's index.
+const boardDragPayload = "BOARD"
+
+// DrawBoardPanel draws the sidebar listing the Project's Boards, where they can be switched between, added,
+// renamed, dragged to reorder them, and deleted.
+func (project *Project) DrawBoardPanel() {
+
+	project.BoardPanel = rl.Rectangle{}
+
+	imgui.SetNextWindowPosV(imgui.Vec2{X: 0, Y: imgui.FrameHeight()}, imgui.ConditionFirstUseEver, imgui.Vec2{})
+	imgui.SetNextWindowSizeV(imgui.Vec2{X: 200, Y: 320}, imgui.ConditionFirstUseEver)
+
+	if imgui.BeginV("Boards", &project.BoardPanelOpen, 0) {
+
+		pos, size := imgui.WindowPos(), imgui.WindowSize()
+		project.BoardPanel = rl.Rectangle{pos.X, pos.Y, size.X, size.Y}
+
+		// Where a Board was dropped to be moved to; we move it after drawing the list so the list doesn't change
+		// while we're going through it.
+		moveFrom, moveTo := -1, -1
+		var deleteBoard *Board
+
+		for i, board := range project.Boards {
+
+			imgui.PushID(strconv.Itoa(i))
+
+			if imgui.SelectableV(fmt.Sprintf("%d. %s (%d)", i+1, board.Name, len(board.Tasks)), i == project.BoardIndex, 0, imgui.Vec2{}) {
+				project.SwitchBoard(i)
+			}
+
+			if imgui.BeginDragDropSource(0) {
+				imgui.SetDragDropPayload(boardDragPayload, []byte(strconv.Itoa(i)), imgui.ConditionAlways)
+				imgui.Text(board.Name)
+				imgui.EndDragDropSource()
+			}
+
+			if imgui.BeginDragDropTarget() {
+				if payload := imgui.AcceptDragDropPayload(boardDragPayload, 0); payload != nil {
+					if from, err := strconv.Atoi(string(payload)); err == nil {
+						moveFrom, moveTo = from, i
+					}
+				}
+				imgui.EndDragDropTarget()
+			}
+
+			if imgui.BeginPopupContextItem() {
+
+				if imgui.MenuItem("Rename...") {
+					project.SwitchBoard(i)
+					project.RenameBoardText = board.Name
+					project.OpenRenameBoardPrompt = true
+				}
+
+				if imgui.MenuItemV("Delete...", "", false, len(project.Boards) > 1) {
+					deleteBoard = board
+				}
+
+				imgui.EndPopup()
+			}
+
+			imgui.PopID()
+
+		}
+
+		if moveFrom >= 0 && moveFrom < len(project.Boards) && moveFrom != moveTo {
+			board := project.Boards[moveFrom]
+			project.MoveBoard(board, moveTo)
+			project.Log("Moved Board %s to position %d.", board.Name, moveTo+1)
+		}
+
+		imgui.Separator()
+
+		if imgui.Button("New Board") {
+			project.AddBoard()
+			project.SwitchBoard(len(project.Boards) - 1)
+		}
+
+		if deleteBoard != nil {
+			project.DeleteBoardPrompt = deleteBoard
+			imgui.OpenPopup("Delete Board")
+		}
+
+		project.drawDeleteBoardPrompt()
+
+	}
+
+	imgui.End()
+
+	project.drawRenameBoardPrompt()
+
+}
+
+func (project *Project) drawRenameBoardPrompt() {
+
+	if project.OpenRenameBoardPrompt {
+		imgui.OpenPopup("Rename Board")
+		project.OpenRenameBoardPrompt = false
+	}
+
+	if imgui.BeginPopupModalV("Rename Board", nil, imgui.WindowFlagsAlwaysAutoResize) {
+
+		if imgui.IsWindowAppearing() {
+			imgui.SetKeyboardFocusHere()
+		}
+
+		entered := imgui.InputTextV("Name", &project.RenameBoardText, imgui.InputTextFlagsEnterReturnsTrue, nil)
+
+		if imgui.Button("Rename") || entered {
+			project.ExecuteDestructiveAction(ActionRenameBoard, project.RenameBoardText)
+			imgui.CloseCurrentPopup()
+		}
+
+		imgui.SameLine()
+
+		if imgui.Button("Cancel") {
+			imgui.CloseCurrentPopup()
+		}
+
+		imgui.EndPopup()
+	}
+
+}
+
+func (project *Project) drawDeleteBoardPrompt() {
+
+	if imgui.BeginPopupModalV("Delete Board", nil, imgui.WindowFlagsAlwaysAutoResize) {
+
+		board := project.DeleteBoardPrompt
+
+		if board == nil {
+			imgui.CloseCurrentPopup()
+		} else {
+
+			imgui.Text(fmt.Sprintf("Delete Board \"%s\" and its %d Task(s)?", board.Name, len(board.Tasks)))
+
+			if imgui.Button("Delete") {
+				project.RemoveBoard(board)
+				project.DeleteBoardPrompt = nil
+				imgui.CloseCurrentPopup()
+			}
+
+			imgui.SameLine()
+
+			if imgui.Button("Cancel") {
+				project.DeleteBoardPrompt = nil
+				imgui.CloseCurrentPopup()
+			}
+
+		}
+
+		imgui.EndPopup()
+	}
+
+}
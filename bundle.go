@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/mholt/archiver"
+	"github.com/ncruces/zenity"
+	"github.com/solarlune/masterplan/model"
+	"github.com/solarlune/masterplan/unzip"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// A bundle is a zip archive of a Project's .plan file along with every file its Tasks use, so it can be passed around
+// as one file and opened anywhere.
+const (
+	BUNDLE_EXTENSION           = ".plan.zip"
+	BUNDLE_PROJECT_FILE        = "project.plan"
+	BUNDLE_RESOURCES_DIRECTORY = "resources"
+)
+
+// Where bundles are extracted to when they're opened, under the user's cache directory.
+const BUNDLES_PATH = "MasterPlan/bundles"
+
+// ExportBundle writes the Project and every file its Tasks use into a bundle at the path given. Remote files are
+// downloaded into the bundle, too, so it doesn't need a connection to open.
+func (project *Project) ExportBundle(bundlePath string) error {
+
+	staging, err := ioutil.TempDir("", "masterplan_bundle")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(staging)
+
+	resourcesPath := filepath.Join(staging, BUNDLE_RESOURCES_DIRECTORY)
+
+	if err := os.MkdirAll(resourcesPath, 0755); err != nil {
+		return err
+	}
+
+	data := project.Serialize()
+
+	bundled := map[string]string{} // The names files have been bundled under, by the paths Tasks refer to them by
+
+	for i, taskData := range gjson.Get(data, `Tasks`).Array() {
+
+		filePath := taskData.Get(`FilePath`)
+
+		if !filePath.Exists() {
+			continue
+		}
+
+		resourcePath := model.DeserializeFilePath(project.FilePath, filePath)
+
+		name, exists := bundled[resourcePath]
+
+		if !exists {
+
+			localFilepath := resourcePath
+			fileName := filepath.Base(resourcePath)
+
+			if model.IsRemotePath(resourcePath) {
+
+				cachedFilepath, err := DefaultHTTPCache().Fetch(resourcePath)
+				if err != nil {
+					project.Log("Could not download [%s] for the bundle; it's left as a link: %s", resourcePath, err.Error())
+					continue
+				}
+
+				localFilepath = cachedFilepath
+				fileName = downloadedFileName(resourcePath, cachedFilepath)
+
+			}
+
+			copiedPath, err := copyIntoDirectory(localFilepath, resourcesPath, fileName)
+
+			if model.IsRemotePath(resourcePath) {
+				DefaultHTTPCache().Release(resourcePath)
+			}
+
+			if err != nil {
+				project.Log("Could not add [%s] to the bundle: %s", resourcePath, err.Error())
+				continue
+			}
+
+			name = filepath.Base(copiedPath)
+			bundled[resourcePath] = name
+
+		}
+
+		// Stored the same way as any other path relative to the .plan file, so the bundled project opens like any other.
+		data, _ = sjson.Set(data, fmt.Sprintf(`Tasks.%d.FilePath`, i), []string{BUNDLE_RESOURCES_DIRECTORY, name})
+
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(staging, BUNDLE_PROJECT_FILE), []byte(data), 0644); err != nil {
+		return err
+	}
+
+	// The archive's made next to where it's going and then moved over, so a failed export doesn't clobber an
+	// earlier bundle by the same name. The archiver goes by the extension to know what kind of archive to make.
+	temporaryPath := bundlePath + ".tmp.zip"
+	os.Remove(temporaryPath)
+
+	if err := archiver.Archive([]string{filepath.Join(staging, BUNDLE_PROJECT_FILE), resourcesPath}, temporaryPath); err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+
+	if err := os.Rename(temporaryPath, bundlePath); err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+
+	project.Log("Exported bundle [%s] with %d files.", bundlePath, len(bundled))
+
+	return nil
+
+}
+
+// ExportBundleAs asks where to export the Project's bundle to, and exports it there.
+func (project *Project) ExportBundleAs() {
+
+	if bundlePath, err := zenity.SelectFileSave(
+		zenity.Title("Select a location and name to export the bundle to."),
+		zenity.ConfirmOverwrite(),
+		zenity.FileFilters{{Name: "MasterPlan bundle", Patterns: []string{"*" + BUNDLE_EXTENSION}}}); err == nil && bundlePath != "" {
+
+		if !strings.HasSuffix(bundlePath, BUNDLE_EXTENSION) {
+			bundlePath = strings.TrimSuffix(bundlePath, ".zip") + BUNDLE_EXTENSION
+		}
+
+		if err := project.ExportBundle(bundlePath); err != nil {
+			project.Log("Could not export bundle [%s]: %s", bundlePath, err.Error())
+		}
+
+	}
+
+}
+
+// OpenBundle extracts the bundle into the cache and opens the Project inside, returning nil if it couldn't. The
+// Project's Tasks point to the extracted files; as the Project itself is only in the cache, it opens unsaved, and
+// the extracted files are moved into its assets folder once it's saved somewhere. The Project sees and reads input
+// through the Viewport and Input given (see NewProject()).
+func OpenBundle(bundlePath string, viewport model.Viewport, input model.Input) *Project {
+
+	absPath, err := filepath.Abs(bundlePath)
+	if err != nil {
+		absPath = bundlePath
+	}
+
+	hash := sha256.Sum256([]byte(absPath))
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(bundlePath), ".zip"), ".plan")
+	bundlesPath := filepath.Join(xdg.CacheHome, BUNDLES_PATH)
+	extractPath := filepath.Join(bundlesPath, name+"_"+hex.EncodeToString(hash[:4]))
+
+	// The folder's removed wholesale below, so it had better be one of ours.
+	if !isWithinDirectory(extractPath, bundlesPath) {
+		logLoadError("Error: Could not open bundle:\n[ %s ]: it can't be extracted to [%s]", bundlePath, extractPath)
+		return nil
+	}
+
+	// Whatever was extracted from the bundle the last time it was opened is replaced.
+	os.RemoveAll(extractPath)
+
+	// Bundles come from anywhere, so they're extracted in a way that can't write outside of the folder.
+	if err := unzip.Extract(bundlePath, extractPath); err != nil {
+		logLoadError("Error: Could not open bundle:\n[ %s ]: %s", bundlePath, err.Error())
+		return nil
+	}
+
+	planPath := filepath.Join(extractPath, BUNDLE_PROJECT_FILE)
+
+	project := LoadProject(planPath, viewport, input)
+
+	if project == nil {
+		return nil
+	}
+
+	project.FilePath = ""
+
+	// The extracted project isn't somewhere to go back to.
+	recent := []string{}
+	for _, path := range programSettings.RecentPlanList {
+		if path != planPath {
+			recent = append(recent, path)
+		}
+	}
+	programSettings.RecentPlanList = recent
+	programSettings.Save()
+
+	// It stays unsaved once it's done loading, as it's not saved anywhere but the cache (see Project.Update()).
+	project.MarkModified()
+	project.Log("Opened bundle [%s]. Save the project somewhere to keep it.", bundlePath)
+
+	return project
+
+}
+
+// OpenBundleFrom asks for a bundle to open, and opens it.
+func OpenBundleFrom(viewport model.Viewport, input model.Input) *Project {
+
+	if bundlePath, err := zenity.SelectFile(
+		zenity.Title("Select MasterPlan Bundle"),
+		zenity.FileFilters{{Name: "MasterPlan bundle", Patterns: []string{"*.zip"}}}); err == nil && bundlePath != "" {
+		return OpenBundle(bundlePath, viewport, input)
+	}
+
+	return nil
+
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	MinZoom = 0.05
+	MaxZoom = 20
+
+	// How much of the way the camera moves towards where it's going each frame.
+	cameraSmoothing = 0.25
+)
+
+// The zoom presets and the zoom levels they go to.
+var zoomPresets = map[string]float32{
+	KBZoomLevel10:   0.1,
+	KBZoomLevel25:   0.25,
+	KBZoomLevel50:   0.5,
+	KBZoomLevel100:  1,
+	KBZoomLevel200:  2,
+	KBZoomLevel400:  4,
+	KBZoomLevel1000: 10,
+}
+
+func screenSize() rl.Vector2 {
+	return rl.Vector2{float32(rl.GetScreenWidth()), float32(rl.GetScreenHeight())}
+}
+
+// ZoomAt sets the zoom level the camera's heading to, keeping the world position under the screen position where
+// it is on screen.
+func (project *Project) ZoomAt(zoom float32, screenPosition rl.Vector2) {
+
+	zoom = float32(math.Max(MinZoom, math.Min(MaxZoom, float64(zoom))))
+
+	// This works from where the camera's going rather than where it is, so zooming repeatedly while the camera's
+	// still moving doesn't drift.
+	fromCenter := rl.Vector2Subtract(screenPosition, rl.Vector2Scale(screenSize(), 0.5))
+	world := rl.Vector2Add(rl.Vector2Scale(project.CameraPan, -1), rl.Vector2Scale(fromCenter, 1/project.Zoom))
+
+	project.Zoom = zoom
+	project.CameraPan = rl.Vector2Subtract(rl.Vector2Scale(fromCenter, 1/zoom), world)
+
+}
+
+// ZoomCentered sets the zoom level the camera's heading to, keeping the center of the screen where it is.
+func (project *Project) ZoomCentered(zoom float32) {
+	project.ZoomAt(zoom, rl.Vector2Scale(screenSize(), 0.5))
+}
+
+// ZoomToFit pans and zooms the camera so that all of the Tasks given are in view.
+func (project *Project) ZoomToFit(tasks []*Task) {
+
+	if len(tasks) == 0 {
+		return
+	}
+
+	bounds := tasks[0].Rect
+	bounds.X, bounds.Y = tasks[0].Position.X, tasks[0].Position.Y
+
+	for _, task := range tasks[1:] {
+		rect := task.Rect
+		rect.X, rect.Y = task.Position.X, task.Position.Y
+		bounds = rectUnion(bounds, rect)
+	}
+
+	// A bit of space around the edges, so the Tasks don't touch the sides of the window
+	margin := float32(project.GridSize) * 4
+	screen := screenSize()
+
+	zoom := float32(math.Min(float64(screen.X/(bounds.Width+margin*2)), float64(screen.Y/(bounds.Height+margin*2))))
+
+	project.Zoom = float32(math.Max(MinZoom, math.Min(MaxZoom, float64(zoom))))
+	project.CameraPan = rl.Vector2{-(bounds.X + bounds.Width/2), -(bounds.Y + bounds.Height/2)}
+
+}
+
+func rectUnion(a, b rl.Rectangle) rl.Rectangle {
+	x := float32(math.Min(float64(a.X), float64(b.X)))
+	y := float32(math.Min(float64(a.Y), float64(b.Y)))
+	right := float32(math.Max(float64(a.X+a.Width), float64(b.X+b.Width)))
+	bottom := float32(math.Max(float64(a.Y+a.Height), float64(b.Y+b.Height)))
+	return rl.Rectangle{x, y, right - x, bottom - y}
+}
+
+// updateCameraAnimation moves the camera's displayed pan and zoom towards where they're heading.
+func (project *Project) updateCameraAnimation() {
+
+	project.Zoom = float32(math.Max(MinZoom, math.Min(MaxZoom, float64(project.Zoom))))
+
+	if project.CameraZoom <= 0 {
+		project.CameraZoom = project.Zoom
+	}
+
+	project.CameraOffset.X += (project.CameraPan.X - project.CameraOffset.X) * cameraSmoothing
+	project.CameraOffset.Y += (project.CameraPan.Y - project.CameraOffset.Y) * cameraSmoothing
+	project.CameraZoom += (project.Zoom - project.CameraZoom) * cameraSmoothing
+
+	if math.Abs(float64(project.CameraPan.X-project.CameraOffset.X))*float64(project.Zoom) < 0.5 {
+		project.CameraOffset.X = project.CameraPan.X
+	}
+
+	if math.Abs(float64(project.CameraPan.Y-project.CameraOffset.Y))*float64(project.Zoom) < 0.5 {
+		project.CameraOffset.Y = project.CameraPan.Y
+	}
+
+	if math.Abs(float64(project.Zoom-project.CameraZoom)) < 0.001 {
+		project.CameraZoom = project.Zoom
+	}
+
+}
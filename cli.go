@@ -0,0 +1,594 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/solarlune/masterplan/model"
+	"github.com/tidwall/gjson"
+)
+
+// A cliCommand is a subcommand of "MasterPlan plan", run on a project file without opening a window.
+type cliCommand struct {
+	Name        string
+	Arguments   string
+	Description string
+	Run         func(flags *flag.FlagSet, args []string) error
+	// Flags defines the command's flags on the FlagSet, if it has any.
+	Flags func(flags *flag.FlagSet)
+}
+
+var cliCommands = []cliCommand{
+	{
+		Name:        "info",
+		Arguments:   "<project.plan>",
+		Description: "Show the project's Boards and how many Tasks they hold.",
+		Run:         cliInfo,
+	},
+	{
+		Name:        "list-tasks",
+		Arguments:   "[-board <board>] <project.plan>",
+		Description: "List the project's Tasks, optionally only those on one Board.",
+		Run:         cliListTasks,
+		Flags:       cliBoardFlag,
+	},
+	{
+		Name:        "add-note",
+		Arguments:   "[-board <board>] [-x <x>] [-y <y>] <project.plan> <text | ->",
+		Description: "Add a note Task; \"-\" reads the note's text from standard input.",
+		Run:         cliAddNote,
+		Flags:       cliTaskFlags,
+	},
+	{
+		Name:        "add-image",
+		Arguments:   "[-board <board>] [-x <x>] [-y <y>] <project.plan> <file | URL>",
+		Description: "Add an image Task showing a local file or a URL.",
+		Run:         cliAddImage,
+		Flags:       cliTaskFlags,
+	},
+	{
+		Name:        "move-board",
+		Arguments:   "<project.plan> <board> <position>",
+		Description: "Move a Board to another position (starting from 1) in the Board list.",
+		Run:         cliMoveBoard,
+	},
+	{
+		Name:        "validate",
+		Arguments:   "<project.plan>",
+		Description: "Check that the project loads and that everything it refers to exists.",
+		Run:         cliValidate,
+	},
+}
+
+// RunCommandLine runs the command given in the arguments headlessly, returning the exit code for the program.
+// Boards are given either by their name or their number, starting from 1.
+func RunCommandLine(args []string) int {
+
+	headless = true
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printCommandLineUsage(os.Stdout)
+		return 0
+	}
+
+	for _, command := range cliCommands {
+
+		if command.Name != args[0] {
+			continue
+		}
+
+		flags := flag.NewFlagSet(command.Name, flag.ContinueOnError)
+		flags.Usage = func() {
+			fmt.Fprintf(os.Stderr, "Usage: MasterPlan plan %s %s\n", command.Name, command.Arguments)
+			flags.PrintDefaults()
+		}
+
+		if command.Flags != nil {
+			command.Flags(flags)
+		}
+
+		if err := flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+
+		if err := command.Run(flags, flags.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+			return 1
+		}
+
+		return 0
+
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command \"%s\".\n\n", args[0])
+	printCommandLineUsage(os.Stderr)
+	return 2
+
+}
+
+func printCommandLineUsage(out io.Writer) {
+
+	fmt.Fprintln(out, "Usage: MasterPlan plan <command> [arguments]")
+	fmt.Fprintln(out, "")
+	fmt.Fprintln(out, "Commands:")
+
+	for _, command := range cliCommands {
+		fmt.Fprintf(out, "  %s %s\n", command.Name, command.Arguments)
+		fmt.Fprintf(out, "      %s\n", command.Description)
+	}
+
+	fmt.Fprintln(out, "")
+	fmt.Fprintln(out, "Boards can be given by name or by number, starting from 1.")
+
+}
+
+func cliBoardFlag(flags *flag.FlagSet) {
+	flags.String("board", "", "the Board to use, by name or number (default: the project's current Board)")
+}
+
+func cliTaskFlags(flags *flag.FlagSet) {
+	cliBoardFlag(flags)
+	flags.Float64("x", 0, "the X position of the new Task (default: below the Board's other Tasks)")
+	flags.Float64("y", 0, "the Y position of the new Task (default: below the Board's other Tasks)")
+}
+
+func cliArguments(args []string, count int, names string) error {
+	if len(args) < count {
+		return fmt.Errorf("expected %s", names)
+	}
+	return nil
+}
+
+// cliOpenProject loads the project file with nothing to look at or click on it, returning nil if it couldn't be
+// loaded.
+func cliOpenProject(path string) *Project {
+	return LoadProject(path, &model.FixedViewport{Size: model.Vector{960, 540}}, model.NoInput{})
+}
+
+func cliLoadProject(path string) (*Project, error) {
+
+	if !FileExists(path) {
+		return nil, fmt.Errorf("project file [ %s ] doesn't exist", path)
+	}
+
+	project := cliOpenProject(path)
+	if project == nil {
+		return nil, fmt.Errorf("could not load project [ %s ]", path)
+	}
+
+	return project, nil
+
+}
+
+func cliSaveProject(project *Project) error {
+
+	if err := project.Save(false); err != nil {
+		return fmt.Errorf("could not save project [ %s ]: %s", project.FilePath, err.Error())
+	}
+
+	return nil
+
+}
+
+// cliBoard returns the Board named (or numbered) by the -board flag, or the project's current Board if it's not set.
+func cliBoard(project *Project, flags *flag.FlagSet) (*Board, error) {
+
+	name := ""
+	if f := flags.Lookup("board"); f != nil {
+		name = f.Value.String()
+	}
+
+	if name == "" {
+		return project.CurrentBoard(), nil
+	}
+
+	return cliFindBoard(project, name)
+
+}
+
+func cliFindBoard(project *Project, name string) (*Board, error) {
+
+	for _, board := range project.Boards {
+		if strings.EqualFold(board.Name, name) {
+			return board, nil
+		}
+	}
+
+	if number, err := strconv.Atoi(name); err == nil {
+		if number >= 1 && number <= len(project.Boards) {
+			return project.Boards[number-1], nil
+		}
+		return nil, fmt.Errorf("there is no Board number %d; the project has %d Board(s)", number, len(project.Boards))
+	}
+
+	return nil, fmt.Errorf("there is no Board named \"%s\"", name)
+
+}
+
+// cliPlaceTask puts a new Task on the Board, either where the -x and -y flags say, or underneath the Board's other Tasks.
+func cliPlaceTask(board *Board, task *Task, flags *flag.FlagSet) {
+
+	position := rl.Vector2{}
+
+	if len(board.Tasks) > 0 {
+
+		left, bottom := board.Tasks[0].Position.X, board.Tasks[0].Position.Y
+
+		for _, t := range board.Tasks {
+
+			height := t.Rect.Height
+			if t.DisplaySize.Y > height {
+				height = t.DisplaySize.Y
+			}
+
+			if t.Position.X < left {
+				left = t.Position.X
+			}
+
+			if t.Position.Y+height > bottom {
+				bottom = t.Position.Y + height
+			}
+
+		}
+
+		position = rl.Vector2{left, bottom + float32(board.Project.GridSize)}
+
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		value, _ := strconv.ParseFloat(f.Value.String(), 32)
+		if f.Name == "x" {
+			position.X = float32(value)
+		} else if f.Name == "y" {
+			position.Y = float32(value)
+		}
+	})
+
+	task.Position = board.Project.LockPositionToGrid(position)
+	task.Rect.X, task.Rect.Y = task.Position.X, task.Position.Y
+
+	board.Tasks = append(board.Tasks, task)
+
+}
+
+func cliInfo(flags *flag.FlagSet, args []string) error {
+
+	if err := cliArguments(args, 1, "a project file"); err != nil {
+		return err
+	}
+
+	project, err := cliLoadProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Destroy()
+
+	// The Project's been upgraded to the current version by now, so the version's read from the file itself.
+	fileData, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	fileVersion, err := model.ProjectFileVersion(string(fileData))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("File:    %s\n", project.FilePath)
+	fmt.Printf("Version: %d (this MasterPlan saves version %d)\n", fileVersion, softwareVersion)
+	fmt.Printf("Tasks:   %d\n", len(project.GetAllTasks()))
+	fmt.Printf("Boards:  %d\n", len(project.Boards))
+
+	for i, board := range project.Boards {
+
+		current := ""
+		if i == project.BoardIndex {
+			current = " (current)"
+		}
+
+		fmt.Printf("  %d. %s: %d Task(s)%s\n", i+1, board.Name, len(board.Tasks), current)
+
+	}
+
+	return nil
+
+}
+
+func cliListTasks(flags *flag.FlagSet, args []string) error {
+
+	if err := cliArguments(args, 1, "a project file"); err != nil {
+		return err
+	}
+
+	project, err := cliLoadProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Destroy()
+
+	boards := project.Boards
+
+	if flags.Lookup("board").Value.String() != "" {
+		board, err := cliBoard(project, flags)
+		if err != nil {
+			return err
+		}
+		boards = []*Board{board}
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "ID\tBOARD\tTYPE\tPOSITION\tCONTENT")
+
+	for _, board := range boards {
+
+		for _, task := range board.Tasks {
+
+			content := task.Description
+			if task.UsesMedia() {
+				content = task.FilePath
+			}
+
+			// Only the first line of multi-line notes, so every Task stays on one line
+			if lines := strings.SplitN(content, "\n", 2); len(lines) > 1 {
+				content = lines[0] + " ..."
+			}
+
+			fmt.Fprintf(out, "%d\t%s\t%s\t%g, %g\t%s\n", task.ID, board.Name, task.TaskType, task.Position.X, task.Position.Y, content)
+
+		}
+
+	}
+
+	return out.Flush()
+
+}
+
+func cliAddNote(flags *flag.FlagSet, args []string) error {
+
+	if err := cliArguments(args, 2, "a project file and the note's text"); err != nil {
+		return err
+	}
+
+	text := strings.Join(args[1:], " ")
+
+	if text == "-" {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		text = strings.TrimRight(string(data), "\r\n")
+	}
+
+	project, err := cliLoadProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Destroy()
+
+	board, err := cliBoard(project, flags)
+	if err != nil {
+		return err
+	}
+
+	task := NewTask(board)
+	task.TaskType = TASK_TYPE_NOTE
+	task.Description = text
+	cliPlaceTask(board, task, flags)
+
+	if err := cliSaveProject(project); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added note Task %d to Board \"%s\".\n", task.ID, board.Name)
+
+	return nil
+
+}
+
+func cliAddImage(flags *flag.FlagSet, args []string) error {
+
+	if err := cliArguments(args, 2, "a project file and an image file or URL"); err != nil {
+		return err
+	}
+
+	imagePath := args[1]
+
+	if !model.IsRemotePath(imagePath) {
+
+		abs, err := filepath.Abs(imagePath)
+		if err != nil {
+			return err
+		}
+
+		if !FileExists(abs) {
+			return fmt.Errorf("image file [ %s ] doesn't exist", imagePath)
+		}
+
+		imagePath = abs
+
+	}
+
+	project, err := cliLoadProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Destroy()
+
+	board, err := cliBoard(project, flags)
+	if err != nil {
+		return err
+	}
+
+	task := NewTask(board)
+	task.TaskType = TASK_TYPE_IMAGE
+	task.FilePath = imagePath
+	task.LoadResource()
+
+	// Without a texture to get the image size from, we read it from the file so the Task shows up at the right size.
+	if file, err := os.Open(imagePath); err == nil {
+		if config, _, err := image.DecodeConfig(file); err == nil {
+			task.DisplaySize = rl.Vector2{float32(config.Width), float32(config.Height)}
+		}
+		file.Close()
+	}
+
+	cliPlaceTask(board, task, flags)
+
+	if err := cliSaveProject(project); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added image Task %d to Board \"%s\".\n", task.ID, board.Name)
+
+	return nil
+
+}
+
+func cliMoveBoard(flags *flag.FlagSet, args []string) error {
+
+	if err := cliArguments(args, 3, "a project file, a Board, and the position to move it to"); err != nil {
+		return err
+	}
+
+	position, err := strconv.Atoi(args[2])
+	if err != nil {
+		return fmt.Errorf("position \"%s\" isn't a number", args[2])
+	}
+
+	project, err := cliLoadProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Destroy()
+
+	board, err := cliFindBoard(project, args[1])
+	if err != nil {
+		return err
+	}
+
+	if position < 1 || position > len(project.Boards) {
+		return fmt.Errorf("position %d is out of range; the project has %d Board(s)", position, len(project.Boards))
+	}
+
+	project.MoveBoard(board, position-1)
+
+	if err := cliSaveProject(project); err != nil {
+		return err
+	}
+
+	fmt.Printf("Moved Board \"%s\" to position %d.\n", board.Name, position)
+
+	return nil
+
+}
+
+func cliValidate(flags *flag.FlagSet, args []string) error {
+
+	if err := cliArguments(args, 1, "a project file"); err != nil {
+		return err
+	}
+
+	path := args[0]
+
+	fileData, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !gjson.Valid(string(fileData)) {
+		return fmt.Errorf("[ %s ] isn't valid JSON", path)
+	}
+
+	jsonData, err := model.MigrateProject(string(fileData))
+	if err != nil {
+		return err
+	}
+
+	problems := []string{}
+
+	problem := func(text string, variables ...interface{}) {
+		problems = append(problems, fmt.Sprintf(text, variables...))
+	}
+
+	data := gjson.Parse(jsonData)
+
+	boardCount := int(data.Get(`BoardCount`).Int())
+	if boardCount < 1 {
+		boardCount = 1
+	}
+
+	if names := data.Get(`BoardNames`).Array(); len(names) != boardCount {
+		problem("BoardCount is %d, but there are %d Board name(s)", boardCount, len(names))
+	}
+
+	if boardIndex := int(data.Get(`BoardIndex`).Int()); boardIndex < 0 || boardIndex >= boardCount {
+		problem("current BoardIndex %d is out of range", boardIndex)
+	}
+
+	tasks := data.Get(`Tasks`)
+
+	if !tasks.IsArray() {
+		problem("Tasks is missing or isn't an array")
+	}
+
+	for i, task := range tasks.Array() {
+
+		if boardIndex := int(task.Get(`BoardIndex`).Int()); boardIndex < 0 || boardIndex >= boardCount {
+			problem("Task #%d is on Board %d, which doesn't exist", i, boardIndex)
+		}
+
+		if !task.Get(`Position\.X`).Exists() || !task.Get(`Position\.Y`).Exists() {
+			problem("Task #%d has no position", i)
+		}
+
+		taskType := task.Get(`TaskType\.CurrentChoice`).String()
+		knownType := false
+		for _, t := range TaskTypes {
+			if t == taskType {
+				knownType = true
+				break
+			}
+		}
+
+		if !knownType {
+			problem("Task #%d has unknown type \"%s\"", i, taskType)
+		}
+
+		if filePath := task.Get(`FilePath`); filePath.Exists() {
+			if resourcePath := model.DeserializeFilePath(path, filePath); !model.IsRemotePath(resourcePath) && !FileExists(resourcePath) {
+				problem("Task #%d refers to [ %s ], which doesn't exist", i, resourcePath)
+			}
+		}
+
+	}
+
+	if project := cliOpenProject(path); project == nil {
+		problem("the project doesn't load")
+	} else {
+		project.Destroy()
+	}
+
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problem(s) in [ %s ]", len(problems), path)
+	}
+
+	fmt.Printf("[ %s ] is valid: %d Task(s) on %d Board(s).\n", path, len(tasks.Array()), boardCount)
+
+	return nil
+
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/solarlune/masterplan/clipboard"
+	"github.com/solarlune/masterplan/model"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// The clipboard currently in use; it's worked out the first time it's needed (see SystemClipboard()).
+var systemClipboard clipboard.Clipboard
+
+// SystemClipboard returns the clipboard to use on this system (see clipboard.System()), falling back to a clipboard
+// that keeps things in the program (with text still going through the window's clipboard) if there isn't one.
+func SystemClipboard() clipboard.Clipboard {
+
+	if systemClipboard == nil {
+
+		if systemClipboard = clipboard.System(); systemClipboard == nil {
+			systemClipboard = NewInProcessClipboard()
+		}
+
+	}
+
+	return systemClipboard
+
+}
+
+// InProcessClipboard is the fallback for systems we don't have clipboard tools for. Text goes through the window's
+// clipboard, so it still works with other programs; anything else only gets as far as this program.
+type InProcessClipboard struct {
+	*clipboard.Memory
+}
+
+func NewInProcessClipboard() InProcessClipboard {
+	return InProcessClipboard{clipboard.NewMemory()}
+}
+
+func (clip InProcessClipboard) Targets() ([]string, error) {
+
+	targets, _ := clip.Memory.Targets()
+
+	if !headless && rl.GetClipboardText() != "" && !clipboard.ContainsTarget(targets, "text/plain") {
+		targets = append([]string{"text/plain"}, targets...)
+	}
+
+	return targets, nil
+
+}
+
+func (clip InProcessClipboard) Read(target string) ([]byte, error) {
+
+	if target == "text/plain" && !headless {
+		return []byte(rl.GetClipboardText()), nil
+	}
+
+	return clip.Memory.Read(target)
+
+}
+
+func (clip InProcessClipboard) Write(items []clipboard.Item) (int, error) {
+
+	others := []clipboard.Item{}
+	wroteText := false
+
+	for _, item := range items {
+		if item.Target == "text/plain" && !headless {
+			rl.SetClipboardText(string(item.Data))
+			wroteText = true
+		} else {
+			others = append(others, item)
+		}
+	}
+
+	// Text from before would otherwise still be offered by the window's clipboard.
+	if !wroteText && !headless {
+		rl.SetClipboardText("")
+	}
+
+	clip.Memory.Write(others)
+
+	return len(items), nil
+
+}
+
+// CopyTasksToClipboard puts the Tasks on the clipboard: as Tasks (see SerializeClipboardTasks()), as the text of the
+// ones that have any, and as a PNG if it's just the one image Task, so they can be pasted into other programs too.
+// The Tasks are offered first, as they're all that goes on clipboards that can only offer one target (like
+// Wayland's, without Xwayland; see clipboard.System()).
+func (board *Board) CopyTasksToClipboard(clip clipboard.Clipboard, tasks []*Task) {
+
+	if len(tasks) == 0 {
+		return
+	}
+
+	data := board.SerializeClipboardTasks(tasks)
+	board.Project.ClipboardTasks = data
+
+	items := []clipboard.Item{{Target: clipboard.TasksTarget, Data: []byte(data)}}
+
+	if len(tasks) == 1 && tasks[0].Is(TASK_TYPE_IMAGE) {
+		if res := board.Project.RetrieveResource(tasks[0].FilePath); res != nil && res.State == RESOURCE_STATE_LOADED {
+			if data, err := encodeClipboardPNG(res.LocalFilepath); err == nil {
+				items = append(items, clipboard.Item{Target: "image/png", Data: data})
+			}
+		}
+	}
+
+	if text := clipboardText(tasks); text != "" {
+		items = append(items, clipboard.Item{Target: "text/plain", Data: []byte(text)})
+	}
+
+	if written, err := clip.Write(items); err != nil {
+		board.Project.Log("Could not copy to the clipboard: %s", err.Error())
+	} else if written < len(items) {
+		board.Project.Log("The clipboard can only hold the Tasks themselves, so they can't be pasted into other programs.")
+	}
+
+}
+
+// clipboardText returns the text of the Tasks that have any, from the top down, one Task per line.
+func clipboardText(tasks []*Task) string {
+
+	sorted := append([]*Task{}, tasks...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Position.Y == sorted[j].Position.Y {
+			return sorted[i].Position.X < sorted[j].Position.X
+		}
+		return sorted[i].Position.Y < sorted[j].Position.Y
+	})
+
+	lines := []string{}
+
+	for _, task := range sorted {
+		if task.HasText() && task.Description != "" {
+			lines = append(lines, task.Description)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+
+}
+
+// encodeClipboardPNG returns the image file as a PNG, converting it if it isn't one already.
+func encodeClipboardPNG(filePath string) ([]byte, error) {
+
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if mimetype.Detect(data).String() == "image/png" {
+		return data, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bytes.Buffer{}
+	if err := png.Encode(&buffer, img); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+
+}
+
+// SerializeClipboardTasks serializes the Tasks, along with the Connections between them, for the clipboard. Unlike
+// in a project file, local files are referred to by their absolute paths, as the Tasks could be pasted into a Project
+// somewhere else.
+func (board *Board) SerializeClipboardTasks(tasks []*Task) string {
+
+	data := `{"Tasks": [], "Connections": []}`
+
+	copied := map[*Task]bool{}
+
+	for _, task := range tasks {
+
+		taskData := task.Serialize()
+
+		if task.UsesMedia() && task.FilePath != "" && !model.IsRemotePath(task.FilePath) {
+			if abs, err := filepath.Abs(task.FilePath); err == nil {
+				taskData, _ = sjson.Set(taskData, `FilePath`, abs)
+			}
+		}
+
+		data, _ = sjson.SetRaw(data, `Tasks.-1`, taskData)
+		copied[task] = true
+
+	}
+
+	for _, connection := range board.Connections {
+		if copied[connection.Start] && copied[connection.End] {
+			data, _ = sjson.SetRaw(data, `Connections.-1`, connection.Serialize())
+		}
+	}
+
+	return data
+
+}
+
+// PasteSerializedTasks adds the Tasks serialized by SerializeClipboardTasks() to the Board, centered on the mouse,
+// returning how many were pasted.
+func (board *Board) PasteSerializedTasks(data string) int {
+
+	payload := gjson.Parse(data)
+
+	pasted := []*Task{}
+	tasksByID := map[int]*Task{} // By the IDs they were copied with, which the Connections refer to
+
+	for _, taskData := range payload.Get(`Tasks`).Array() {
+
+		task := board.CreateNewTask()
+		task.Deserialize(taskData.String())
+
+		tasksByID[task.ID] = task
+		task.ID = board.Project.FirstFreeID()
+
+		pasted = append(pasted, task)
+
+	}
+
+	if len(pasted) == 0 {
+		return 0
+	}
+
+	for _, connectionData := range payload.Get(`Connections`).Array() {
+		if connection := ConnectionFromData(model.ParseConnectionData(connectionData.Raw), tasksByID); connection != nil {
+			board.Connections = append(board.Connections, connection)
+			board.Project.UndoHistory.CaptureConnection(connection)
+		}
+	}
+
+	// The Tasks are centered by their positions, rather than their Rects, as their files may not have loaded yet.
+	positions := []model.Rect{}
+	for _, task := range pasted {
+		positions = append(positions, model.Rect{X: task.Position.X, Y: task.Position.Y})
+	}
+
+	pastePositions := model.PastePositions(positions, board.Project.Viewport.WorldMousePosition(), board.Project.GridSize)
+
+	for i, task := range pasted {
+		task.Position = rlVector(pastePositions[i])
+		task.Rect.X, task.Rect.Y = task.Position.X, task.Position.Y
+		board.RemoveTaskFromGrid(task)
+		board.AddTaskToGrid(task)
+		board.Project.UndoHistory.Capture(task)
+	}
+
+	board.ReorderTasks()
+
+	return len(pasted)
+
+}
+
+// pasteClipboardTasks pastes the Tasks read from the clipboard, if there are any, returning if there were.
+func (board *Board) pasteClipboardTasks(paste clipboard.Paste) bool {
+
+	if paste.Kind != clipboard.PasteTasks {
+		return false
+	}
+
+	if count := board.PasteSerializedTasks(string(paste.Data)); count > 0 {
+		board.Project.Log("Pasted %d Tasks from the clipboard.", count)
+		return true
+	}
+
+	return false
+
+}
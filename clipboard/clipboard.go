@@ -0,0 +1,186 @@
+// Package clipboard gets at the system clipboard through whatever tools the system has for it, and works out what
+// its contents would be pasted as.
+package clipboard
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// Clipboard is a way of getting at the system clipboard. Things on the clipboard can be offered as several different
+// targets (MIME types like "image/png", or X11 names like "UTF8_STRING"), and are read as one of them.
+type Clipboard interface {
+	// Targets returns the targets the clipboard's contents are offered as.
+	Targets() ([]string, error)
+	// Read returns the clipboard's contents as the target given.
+	Read(target string) ([]byte, error)
+	// Write puts the items on the clipboard, replacing what was there; they're the same thing offered as different
+	// targets, in order of preference. It returns how many of the items, from the first, made it onto the clipboard,
+	// as not every clipboard can offer more than one target.
+	Write(items []Item) (int, error)
+}
+
+// Item is data to go on the clipboard, along with the target it's offered as.
+type Item struct {
+	Target string
+	Data   []byte
+}
+
+// The target MasterPlan Tasks are offered on the clipboard as, so they can be pasted into another Project (even in
+// another MasterPlan window).
+const TasksTarget = "application/x-masterplan-tasks"
+
+// Memory keeps its contents in memory, without touching the system clipboard at all; it's what's used when there's
+// no system clipboard to use, and can be filled out ahead of time to stand in for the system clipboard.
+type Memory struct {
+	Contents map[string][]byte
+	Order    []string // The targets in Contents, in the order they're offered
+}
+
+func NewMemory() *Memory {
+	return &Memory{Contents: map[string][]byte{}, Order: []string{}}
+}
+
+func (clip *Memory) Targets() ([]string, error) {
+	return append([]string{}, clip.Order...), nil
+}
+
+func (clip *Memory) Read(target string) ([]byte, error) {
+	data, exists := clip.Contents[target]
+	if !exists {
+		return nil, fmt.Errorf("nothing on the clipboard as %s", target)
+	}
+	return data, nil
+}
+
+func (clip *Memory) Write(items []Item) (int, error) {
+
+	clip.Contents = map[string][]byte{}
+	clip.Order = []string{}
+
+	for _, item := range items {
+		clip.Contents[item.Target] = item.Data
+		clip.Order = append(clip.Order, item.Target)
+	}
+
+	return len(items), nil
+
+}
+
+// ContainsTarget returns if the target's among the targets, ignoring case.
+func ContainsTarget(targets []string, target string) bool {
+	for _, t := range targets {
+		if strings.EqualFold(t, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTextTarget returns if the clipboard target is plain text.
+func IsTextTarget(target string) bool {
+	switch strings.ToUpper(target) {
+	case "STRING", "UTF8_STRING", "TEXT":
+		return true
+	}
+	return strings.HasPrefix(strings.ToLower(target), "text/plain")
+}
+
+// IsImageTarget returns if the clipboard target is an image.
+func IsImageTarget(target string) bool {
+	return strings.HasPrefix(strings.ToLower(target), "image/")
+}
+
+// What the clipboard's contents are pasted as.
+const (
+	PasteNothing = iota
+	PasteTasks
+	PasteText
+	PasteImage
+)
+
+// Paste is what's on the clipboard, as it's pasted into a Project.
+type Paste struct {
+	Kind   int    // One of the Paste constants
+	Target string // The target it was read as
+	Data   []byte
+	// The extension for the type of image the data actually is, which could be different from what the target
+	// says it is.
+	Extension string
+}
+
+// ReadPaste reads what's on the clipboard to be pasted. MasterPlan Tasks come first; otherwise, it's the first text
+// or image the clipboard offers, going by the order the clipboard offers them in. Data offered as an image that
+// isn't one returns an error, rather than being saved as something that can't be shown.
+func ReadPaste(clip Clipboard) (Paste, error) {
+
+	targets, err := clip.Targets()
+	if err != nil {
+		return Paste{}, err
+	}
+
+	if ContainsTarget(targets, TasksTarget) {
+
+		data, err := clip.Read(TasksTarget)
+		if err != nil {
+			return Paste{}, err
+		}
+
+		return Paste{Kind: PasteTasks, Target: TasksTarget, Data: data}, nil
+
+	}
+
+	for _, target := range targets {
+
+		if !IsTextTarget(target) && !IsImageTarget(target) {
+			continue
+		}
+
+		data, err := clip.Read(target)
+		if err != nil {
+			return Paste{}, err
+		}
+
+		if IsTextTarget(target) {
+			return Paste{Kind: PasteText, Target: target, Data: data}, nil
+		}
+
+		fileType := mimetype.Detect(data)
+		if !strings.HasPrefix(fileType.String(), "image/") {
+			return Paste{}, fmt.Errorf("clipboard data offered as %s isn't an image (%s)", target, fileType.String())
+		}
+
+		return Paste{Kind: PasteImage, Target: target, Data: data, Extension: fileType.Extension()}, nil
+
+	}
+
+	return Paste{}, nil
+
+}
+
+// SaveImage saves a pasted image into the directory, under the name given along with the extension for the type of
+// image it is, returning the path it was saved to.
+func (paste Paste) SaveImage(directory, name string) (string, error) {
+
+	if paste.Kind != PasteImage {
+		return "", fmt.Errorf("what was pasted isn't an image")
+	}
+
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return "", err
+	}
+
+	savePath := filepath.Join(directory, name) + paste.Extension
+
+	if err := ioutil.WriteFile(savePath, paste.Data, 0644); err != nil {
+		return "", err
+	}
+
+	return savePath, nil
+
+}
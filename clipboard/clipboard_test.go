@@ -0,0 +1,119 @@
+package clipboard
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testPNG(t *testing.T) []byte {
+
+	buffer := bytes.Buffer{}
+
+	if err := png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+
+}
+
+func TestReadPasteText(t *testing.T) {
+
+	clip := NewMemory()
+	clip.Write([]Item{{"TARGETS", []byte("")}, {"UTF8_STRING", []byte("Buy more coffee")}})
+
+	paste, err := ReadPaste(clip)
+	if err != nil {
+		t.Fatalf("ReadPaste() returned an error: %s", err)
+	}
+
+	if paste.Kind != PasteText || string(paste.Data) != "Buy more coffee" {
+		t.Errorf("got %+v, want the text to be pasted as a note", paste)
+	}
+
+}
+
+func TestReadPasteImage(t *testing.T) {
+
+	data := testPNG(t)
+
+	// The image is offered as a JPEG, but it's really a PNG.
+	clip := NewMemory()
+	clip.Write([]Item{{"image/jpeg", data}, {"text/plain", []byte("cat.png")}})
+
+	paste, err := ReadPaste(clip)
+	if err != nil {
+		t.Fatalf("ReadPaste() returned an error: %s", err)
+	}
+
+	if paste.Kind != PasteImage {
+		t.Fatalf("got %+v, want an image", paste)
+	}
+
+	directory, err := ioutil.TempDir("", "clipboard")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(directory)
+
+	savePath, err := paste.SaveImage(filepath.Join(directory, "pasted"), "image")
+	if err != nil {
+		t.Fatalf("SaveImage() returned an error: %s", err)
+	}
+
+	if want := filepath.Join(directory, "pasted", "image.png"); savePath != want {
+		t.Errorf("image saved to [%s], want [%s]", savePath, want)
+	}
+
+	if saved, err := ioutil.ReadFile(savePath); err != nil || !bytes.Equal(saved, data) {
+		t.Errorf("saved image doesn't match what was pasted (%v)", err)
+	}
+
+}
+
+func TestReadPasteRejectsNonImages(t *testing.T) {
+
+	clip := NewMemory()
+	clip.Write([]Item{{"image/png", []byte("This isn't an image at all.")}})
+
+	if paste, err := ReadPaste(clip); err == nil {
+		t.Errorf("got %+v, want an error for text offered as an image", paste)
+	}
+
+}
+
+func TestReadPasteTasksFirst(t *testing.T) {
+
+	tasks := []byte(`{"Tasks": [], "Connections": []}`)
+
+	// The Tasks come first even when they're offered last.
+	clip := NewMemory()
+	clip.Write([]Item{{"image/png", testPNG(t)}, {"text/plain", []byte("Buy more coffee")}, {TasksTarget, tasks}})
+
+	paste, err := ReadPaste(clip)
+	if err != nil {
+		t.Fatalf("ReadPaste() returned an error: %s", err)
+	}
+
+	if paste.Kind != PasteTasks || !bytes.Equal(paste.Data, tasks) {
+		t.Errorf("got %+v, want the Tasks", paste)
+	}
+
+}
+
+func TestReadPasteNothing(t *testing.T) {
+
+	clip := NewMemory()
+	clip.Write([]Item{{"application/pdf", []byte("%PDF-1.4")}})
+
+	if paste, err := ReadPaste(clip); err != nil || paste.Kind != PasteNothing {
+		t.Errorf("got %+v (%v), want nothing to paste", paste, err)
+	}
+
+}
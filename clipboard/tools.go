@@ -0,0 +1,153 @@
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// System returns the clipboard to use on this system, or nil if there are no clipboard tools to use. Reading the
+// clipboard goes through external tools, so they have to be installed: xclip under X11, or wl-clipboard's wl-paste
+// and wl-copy under Wayland. X11 comes first, as it can offer more than one target, and Wayland sessions run X11
+// programs (like MasterPlan's window) through Xwayland anyway, which passes the clipboard between them.
+func System() Clipboard {
+
+	if os.Getenv("DISPLAY") != "" && commandExists("xclip") {
+		return &X11{}
+	}
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" && commandExists("wl-paste") && commandExists("wl-copy") {
+		return Wayland{}
+	}
+
+	return nil
+
+}
+
+func commandExists(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// runCommand runs a clipboard tool, returning what it wrote out. If it fails, the error includes what the tool had
+// to say about it.
+func runCommand(name string, args ...string) ([]byte, error) {
+
+	output, err := exec.Command(name, args...).Output()
+
+	if exitError, ok := err.(*exec.ExitError); ok && len(exitError.Stderr) > 0 {
+		err = fmt.Errorf("%s: %s", name, strings.TrimSpace(string(exitError.Stderr)))
+	}
+
+	return output, err
+
+}
+
+// writeCommand hands the data to a clipboard tool, waiting for it to exit. That's as soon as it's read the data, as
+// the tools fork into the background to keep serving the clipboard. Their output isn't captured, as the copy in the
+// background would hold the pipe open, and we'd wait on it, too.
+func writeCommand(data []byte, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	return cmd.Run()
+}
+
+// splitTargets splits up a list of targets, one per line, as the clipboard tools print them.
+func splitTargets(output []byte) []string {
+
+	targets := []string{}
+
+	for _, target := range strings.Split(strings.Replace(string(output), "\r\n", "\n", -1), "\n") {
+		if target = strings.TrimSpace(target); target != "" {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+
+}
+
+// X11 reads the clipboard through xclip, so xclip has to be installed. Writing to it, the program owns the clipboard
+// itself (see selectionOwner), so it can offer every item; the clipboard's contents last as long as the program does,
+// unless there's a clipboard manager to keep them.
+type X11 struct {
+	mutex sync.Mutex
+	owner *selectionOwner
+}
+
+func (clip *X11) Targets() ([]string, error) {
+	output, err := runCommand("xclip", "-selection", "clipboard", "-t", "TARGETS", "-o")
+	if err != nil {
+		return nil, err
+	}
+	return splitTargets(output), nil
+}
+
+func (clip *X11) Read(target string) ([]byte, error) {
+	return runCommand("xclip", "-selection", "clipboard", "-t", target, "-o")
+}
+
+// Write offers every item, unless the program can't connect to the X server itself, in which case it falls back to
+// xclip, which can only offer the first.
+func (clip *X11) Write(items []Item) (int, error) {
+
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	owner, err := ownSelection(os.Getenv("DISPLAY"), items)
+
+	if err != nil {
+		if err := writeCommand(items[0].Data, "xclip", "-selection", "clipboard", "-t", items[0].Target, "-i"); err != nil {
+			return 0, err
+		}
+		owner = nil
+	}
+
+	clip.mutex.Lock()
+	previous := clip.owner
+	clip.owner = owner
+	clip.mutex.Unlock()
+
+	// The new owner's already taken the clipboard, so there's no moment without anything on it.
+	if previous != nil {
+		previous.Close()
+	}
+
+	if owner == nil {
+		return 1, nil
+	}
+
+	return len(items), nil
+
+}
+
+// Wayland uses wl-clipboard's wl-paste and wl-copy to get at the clipboard, so wl-clipboard has to be installed.
+type Wayland struct{}
+
+func (clip Wayland) Targets() ([]string, error) {
+	output, err := runCommand("wl-paste", "--list-types")
+	if err != nil {
+		return nil, err
+	}
+	return splitTargets(output), nil
+}
+
+func (clip Wayland) Read(target string) ([]byte, error) {
+	return runCommand("wl-paste", "--no-newline", "--type", target)
+}
+
+// Write only offers the first item, as wl-copy can only offer one type at a time. It's only used without X11 (see
+// System()).
+func (clip Wayland) Write(items []Item) (int, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	if err := writeCommand(items[0].Data, "wl-copy", "--type", items[0].Target); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
@@ -0,0 +1,638 @@
+package clipboard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// What selectionOwner uses of the X11 protocol; see the X Window System Protocol specification and the ICCCM.
+const (
+	x11CreateWindow           = 1
+	x11ChangeWindowAttributes = 2
+	x11InternAtom             = 16
+	x11ChangeProperty         = 18
+	x11SetSelectionOwner      = 22
+	x11SendEvent              = 25
+
+	x11Error            = 0
+	x11Reply            = 1
+	x11PropertyNotify   = 28
+	x11SelectionClear   = 29
+	x11SelectionRequest = 30
+	x11SelectionNotify  = 31
+
+	x11PropertyDeleted    = 1
+	x11EventMask          = 0x800
+	x11PropertyChangeMask = 0x400000
+	x11InputOnly          = 2
+	x11FormatAtoms        = 32
+	x11FormatBytes        = 8
+)
+
+var x11ByteOrder = binary.LittleEndian
+
+// selectionOwner owns the X11 CLIPBOARD selection, offering each item as its own target, for as long as its
+// connection to the X server is open (or until something else takes the clipboard). Text is offered as the
+// targets X11 programs ask for text by, too. This is what xclip does, but xclip can only offer one target at a time.
+type selectionOwner struct {
+	conn     io.ReadWriteCloser
+	window   uint32
+	maxChunk int // The most data that fits in one request; anything larger's sent in chunks (the ICCCM's INCR)
+
+	atoms     map[string]uint32
+	targets   []uint32 // In order of preference, for answering TARGETS
+	offers    map[uint32]selectionOffer
+	transfers map[selectionTransferKey]*selectionTransfer
+}
+
+type selectionOffer struct {
+	Type uint32 // The type the data's stored as, which is its target, except for text asked for as "TEXT"
+	Data []byte
+}
+
+type selectionTransferKey struct {
+	Requestor uint32
+	Property  uint32
+}
+
+// selectionTransfer is an offer being sent in chunks, as the requestor deletes each chunk it's read.
+type selectionTransfer struct {
+	Offer selectionOffer
+	Sent  int
+}
+
+// ownSelection connects to the X server the DISPLAY given is for and takes ownership of the clipboard, offering the
+// items on it.
+func ownSelection(display string, items []Item) (*selectionOwner, error) {
+
+	conn, number, err := dialX11(display)
+	if err != nil {
+		return nil, err
+	}
+
+	authName, authData := x11Authorization(conn, number)
+
+	owner, err := newSelectionOwner(conn, authName, authData, items)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return owner, nil
+
+}
+
+// newSelectionOwner sets up the connection to the X server and takes ownership of the clipboard, serving requests
+// for it in the background from then on.
+func newSelectionOwner(conn io.ReadWriteCloser, authName string, authData []byte, items []Item) (*selectionOwner, error) {
+
+	owner := &selectionOwner{
+		conn:      conn,
+		atoms:     map[string]uint32{},
+		offers:    map[uint32]selectionOffer{},
+		transfers: map[selectionTransferKey]*selectionTransfer{},
+	}
+
+	root, idBase, idMask, maxRequestLength, err := owner.setup(authName, authData)
+	if err != nil {
+		return nil, err
+	}
+
+	owner.window = idBase | (idMask & -idMask)
+
+	// A ChangeProperty request has 24 bytes of its own, and its length is counted in 4 byte units.
+	owner.maxChunk = (maxRequestLength*4 - 24) &^ 3
+	if owner.maxChunk < 4 {
+		return nil, fmt.Errorf("X server's maximum request length of %d is too short", maxRequestLength)
+	}
+
+	for _, name := range []string{"CLIPBOARD", "TARGETS", "ATOM", "INCR", "UTF8_STRING"} {
+		if _, err := owner.atom(name); err != nil {
+			return nil, err
+		}
+	}
+
+	owner.targets = append(owner.targets, owner.atoms["TARGETS"])
+
+	for _, item := range items {
+
+		names := []string{item.Target}
+
+		if IsTextTarget(item.Target) {
+			names = append(names, "UTF8_STRING", "text/plain;charset=utf-8", "text/plain", "STRING", "TEXT")
+		}
+
+		for _, name := range names {
+
+			target, err := owner.atom(name)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, exists := owner.offers[target]; exists {
+				continue
+			}
+
+			offer := selectionOffer{Type: target, Data: item.Data}
+
+			// TEXT asks for text in whatever encoding the owner likes.
+			if name == "TEXT" {
+				offer.Type = owner.atoms["UTF8_STRING"]
+			}
+
+			owner.offers[target] = offer
+			owner.targets = append(owner.targets, target)
+
+		}
+
+	}
+
+	// The window's only there to own the clipboard, so it's never shown.
+	err = owner.send(x11CreateWindow, 0, func(r *x11Request) {
+		r.u32(owner.window)
+		r.u32(root)
+		r.u16(0) // X
+		r.u16(0) // Y
+		r.u16(1) // Width
+		r.u16(1) // Height
+		r.u16(0) // Border width
+		r.u16(x11InputOnly)
+		r.u32(0) // Visual, copied from the parent
+		r.u32(0) // No attributes
+	})
+
+	if err == nil {
+		err = owner.send(x11SetSelectionOwner, 0, func(r *x11Request) {
+			r.u32(owner.window)
+			r.u32(owner.atoms["CLIPBOARD"])
+			r.u32(0) // The current time
+		})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	go owner.serve()
+
+	return owner, nil
+
+}
+
+// Close gives up the clipboard, if it's still owned.
+func (owner *selectionOwner) Close() {
+	owner.conn.Close()
+}
+
+// setup sends the connection setup, returning the root window of the first screen, the range of IDs the connection
+// can give its resources, and the maximum request length (in 4 byte units).
+func (owner *selectionOwner) setup(authName string, authData []byte) (uint32, uint32, uint32, int, error) {
+
+	r := &x11Request{}
+	r.u8('l') // Little endian
+	r.u8(0)
+	r.u16(11) // Protocol version 11.0
+	r.u16(0)
+	r.u16(uint16(len(authName)))
+	r.u16(uint16(len(authData)))
+	r.u16(0)
+	r.Write([]byte(authName))
+	r.pad()
+	r.Write(authData)
+	r.pad()
+
+	if _, err := owner.conn.Write(r.Bytes()); err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(owner.conn, header); err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	data := make([]byte, int(x11ByteOrder.Uint16(header[6:8]))*4)
+	if _, err := io.ReadFull(owner.conn, data); err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	if header[0] != 1 {
+		reason := string(data)
+		if header[0] == 0 && int(header[1]) <= len(data) {
+			reason = string(data[:header[1]])
+		}
+		return 0, 0, 0, 0, fmt.Errorf("X server refused the connection: %s", strings.TrimSpace(reason))
+	}
+
+	if len(data) < 32 {
+		return 0, 0, 0, 0, fmt.Errorf("X server's connection setup is too short")
+	}
+
+	vendorLength := int(x11ByteOrder.Uint16(data[16:18]))
+	formatCount := int(data[21])
+	screenOffset := 32 + (vendorLength+3)&^3 + formatCount*8
+
+	if len(data) < screenOffset+4 {
+		return 0, 0, 0, 0, fmt.Errorf("X server didn't describe any screens")
+	}
+
+	idBase := x11ByteOrder.Uint32(data[4:8])
+	idMask := x11ByteOrder.Uint32(data[8:12])
+	maxRequestLength := int(x11ByteOrder.Uint16(data[18:20]))
+	root := x11ByteOrder.Uint32(data[screenOffset : screenOffset+4])
+
+	return root, idBase, idMask, maxRequestLength, nil
+
+}
+
+// atom returns the atom for the name, asking the X server for it if it hasn't been already. It's only used before
+// the connection's served in the background, so nothing else is reading from it.
+func (owner *selectionOwner) atom(name string) (uint32, error) {
+
+	if atom, exists := owner.atoms[name]; exists {
+		return atom, nil
+	}
+
+	err := owner.send(x11InternAtom, 0, func(r *x11Request) {
+		r.u16(uint16(len(name)))
+		r.u16(0)
+		r.Write([]byte(name))
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	packet := make([]byte, 32)
+
+	for {
+
+		if _, err := io.ReadFull(owner.conn, packet); err != nil {
+			return 0, err
+		}
+
+		switch packet[0] {
+
+		case x11Error:
+			return 0, fmt.Errorf("X server couldn't intern atom %s (error %d)", name, packet[1])
+
+		case x11Reply:
+
+			if extra := x11ByteOrder.Uint32(packet[4:8]); extra > 0 {
+				if _, err := io.CopyN(ioutil.Discard, owner.conn, int64(extra)*4); err != nil {
+					return 0, err
+				}
+			}
+
+			atom := x11ByteOrder.Uint32(packet[8:12])
+			owner.atoms[name] = atom
+			return atom, nil
+
+		}
+
+	}
+
+}
+
+// serve answers requests for the clipboard's contents until something else takes the clipboard, or the connection's
+// closed.
+func (owner *selectionOwner) serve() {
+
+	defer owner.conn.Close()
+
+	packet := make([]byte, 32)
+
+	for {
+
+		if _, err := io.ReadFull(owner.conn, packet); err != nil {
+			return
+		}
+
+		var err error
+
+		// Events sent by other clients have the top bit set.
+		switch packet[0] & 0x7f {
+
+		case x11Reply:
+			_, err = io.CopyN(ioutil.Discard, owner.conn, int64(x11ByteOrder.Uint32(packet[4:8]))*4)
+
+		case x11SelectionClear:
+			return
+
+		case x11SelectionRequest:
+			err = owner.answer(
+				x11ByteOrder.Uint32(packet[4:8]),   // Time
+				x11ByteOrder.Uint32(packet[12:16]), // Requestor
+				x11ByteOrder.Uint32(packet[16:20]), // Selection
+				x11ByteOrder.Uint32(packet[20:24]), // Target
+				x11ByteOrder.Uint32(packet[24:28]), // Property
+			)
+
+		case x11PropertyNotify:
+			if packet[16] == x11PropertyDeleted {
+				err = owner.continueTransfer(x11ByteOrder.Uint32(packet[4:8]), x11ByteOrder.Uint32(packet[8:12]))
+			}
+
+		}
+
+		// Errors from the X server (like a requestor's window having gone away) don't matter; failing to write to it
+		// means the connection's gone.
+		if err != nil {
+			return
+		}
+
+	}
+
+}
+
+// answer puts the clipboard's contents as the target asked for into the requestor's property, and lets the requestor
+// know it's there (or that it can't have it, as that target).
+func (owner *selectionOwner) answer(time, requestor, selection, target, property uint32) error {
+
+	// Requestors from before the ICCCM don't say which property they want the data in.
+	if property == 0 {
+		property = target
+	}
+
+	if target == owner.atoms["TARGETS"] {
+
+		targets := make([]byte, len(owner.targets)*4)
+		for i, t := range owner.targets {
+			x11ByteOrder.PutUint32(targets[i*4:], t)
+		}
+
+		if err := owner.changeProperty(requestor, property, owner.atoms["ATOM"], x11FormatAtoms, targets); err != nil {
+			return err
+		}
+
+	} else if offer, exists := owner.offers[target]; exists {
+
+		if len(offer.Data) <= owner.maxChunk {
+
+			if err := owner.changeProperty(requestor, property, offer.Type, x11FormatBytes, offer.Data); err != nil {
+				return err
+			}
+
+		} else {
+
+			// It's too large to send in one go, so the requestor's told how large it is, and each chunk's sent once it's
+			// deleted the last one.
+			err := owner.send(x11ChangeWindowAttributes, 0, func(r *x11Request) {
+				r.u32(requestor)
+				r.u32(x11EventMask)
+				r.u32(x11PropertyChangeMask)
+			})
+
+			if err != nil {
+				return err
+			}
+
+			size := make([]byte, 4)
+			x11ByteOrder.PutUint32(size, uint32(len(offer.Data)))
+
+			if err := owner.changeProperty(requestor, property, owner.atoms["INCR"], x11FormatAtoms, size); err != nil {
+				return err
+			}
+
+			owner.transfers[selectionTransferKey{requestor, property}] = &selectionTransfer{Offer: offer}
+
+		}
+
+	} else {
+		property = 0 // Not something that's on the clipboard
+	}
+
+	return owner.send(x11SendEvent, 0, func(r *x11Request) {
+		r.u32(requestor)
+		r.u32(0) // No event mask; it goes to the client that made the window
+		r.u8(x11SelectionNotify)
+		r.u8(0)
+		r.u16(0)
+		r.u32(time)
+		r.u32(requestor)
+		r.u32(selection)
+		r.u32(target)
+		r.u32(property)
+		r.Write(make([]byte, 8))
+	})
+
+}
+
+// continueTransfer sends the next chunk of a transfer once the requestor's deleted the property holding the last
+// one. It ends with an empty chunk.
+func (owner *selectionOwner) continueTransfer(requestor, property uint32) error {
+
+	key := selectionTransferKey{requestor, property}
+
+	transfer, exists := owner.transfers[key]
+	if !exists {
+		return nil
+	}
+
+	end := transfer.Sent + owner.maxChunk
+	if end > len(transfer.Offer.Data) {
+		end = len(transfer.Offer.Data)
+	}
+
+	chunk := transfer.Offer.Data[transfer.Sent:end]
+	transfer.Sent = end
+
+	if len(chunk) == 0 {
+		delete(owner.transfers, key)
+	}
+
+	return owner.changeProperty(requestor, property, transfer.Offer.Type, x11FormatBytes, chunk)
+
+}
+
+func (owner *selectionOwner) changeProperty(window, property, propertyType uint32, format byte, data []byte) error {
+	return owner.send(x11ChangeProperty, 0, func(r *x11Request) {
+		r.u32(window)
+		r.u32(property)
+		r.u32(propertyType)
+		r.u8(format)
+		r.Write([]byte{0, 0, 0})
+		r.u32(uint32(len(data) / int(format/8)))
+		r.Write(data)
+	})
+}
+
+// send sends a request to the X server; build writes out everything after the request's opcode, detail byte and
+// length, which are worked out here.
+func (owner *selectionOwner) send(opcode, detail byte, build func(r *x11Request)) error {
+
+	body := &x11Request{}
+	build(body)
+	body.pad()
+
+	r := &x11Request{}
+	r.u8(opcode)
+	r.u8(detail)
+	r.u16(uint16((4 + body.Len()) / 4))
+	r.Write(body.Bytes())
+
+	_, err := owner.conn.Write(r.Bytes())
+	return err
+
+}
+
+// x11Request builds up a request to send to the X server.
+type x11Request struct {
+	bytes.Buffer
+}
+
+func (r *x11Request) u8(v byte) {
+	r.WriteByte(v)
+}
+
+func (r *x11Request) u16(v uint16) {
+	b := make([]byte, 2)
+	x11ByteOrder.PutUint16(b, v)
+	r.Write(b)
+}
+
+func (r *x11Request) u32(v uint32) {
+	b := make([]byte, 4)
+	x11ByteOrder.PutUint32(b, v)
+	r.Write(b)
+}
+
+// pad pads the request out to a multiple of 4 bytes, as everything in the protocol is.
+func (r *x11Request) pad() {
+	for r.Len()%4 != 0 {
+		r.WriteByte(0)
+	}
+}
+
+// dialX11 connects to the X server the DISPLAY given is for (e.g. ":0", "localhost:10.0"), returning the connection
+// and the display's number.
+func dialX11(display string) (net.Conn, string, error) {
+
+	colon := strings.LastIndex(display, ":")
+	if colon < 0 {
+		return nil, "", fmt.Errorf("DISPLAY %q isn't valid", display)
+	}
+
+	host := display[:colon]
+	number := display[colon+1:]
+	if dot := strings.Index(number, "."); dot >= 0 {
+		number = number[:dot]
+	}
+
+	if _, err := strconv.Atoi(number); err != nil {
+		return nil, "", fmt.Errorf("DISPLAY %q isn't valid", display)
+	}
+
+	// macOS's XQuartz sets DISPLAY to the path of its socket.
+	if strings.HasPrefix(host, "/") {
+		conn, err := net.Dial("unix", display)
+		return conn, number, err
+	}
+
+	if host == "" || host == "unix" {
+
+		socketPath := "/tmp/.X11-unix/X" + number
+
+		conn, err := net.Dial("unix", socketPath)
+		if err != nil {
+			// Linux X servers listen on an abstract socket, too.
+			if abstractConn, abstractErr := net.Dial("unix", "@"+socketPath); abstractErr == nil {
+				return abstractConn, number, nil
+			}
+		}
+
+		return conn, number, err
+
+	}
+
+	displayNumber, _ := strconv.Atoi(number)
+	conn, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(6000+displayNumber)))
+	return conn, number, err
+
+}
+
+// The families of addresses in an Xauthority file.
+const (
+	xauthFamilyLocal = 256
+	xauthFamilyWild  = 65535
+)
+
+// x11Authorization returns the MIT-MAGIC-COOKIE-1 for the display from the user's Xauthority file, if there is
+// one; otherwise, the connection's made without any, which some X servers accept from the same machine.
+func x11Authorization(conn net.Conn, number string) (string, []byte) {
+
+	authPath := os.Getenv("XAUTHORITY")
+
+	if authPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil
+		}
+		authPath = filepath.Join(home, ".Xauthority")
+	}
+
+	data, err := ioutil.ReadFile(authPath)
+	if err != nil {
+		return "", nil
+	}
+
+	hostname, _ := os.Hostname()
+	local := conn.RemoteAddr().Network() == "unix"
+
+	return xauthCookie(data, hostname, number, local)
+
+}
+
+// xauthCookie finds the MIT-MAGIC-COOKIE-1 for the display in the contents of an Xauthority file; local is whether
+// the display's on this machine.
+func xauthCookie(data []byte, hostname, number string, local bool) (string, []byte) {
+
+	const cookieName = "MIT-MAGIC-COOKIE-1"
+
+	readField := func() ([]byte, bool) {
+		if len(data) < 2 {
+			return nil, false
+		}
+		length := int(binary.BigEndian.Uint16(data[:2]))
+		if len(data) < 2+length {
+			return nil, false
+		}
+		field := data[2 : 2+length]
+		data = data[2+length:]
+		return field, true
+	}
+
+	for len(data) >= 2 {
+
+		family := binary.BigEndian.Uint16(data[:2])
+		data = data[2:]
+
+		address, ok1 := readField()
+		entryNumber, ok2 := readField()
+		name, ok3 := readField()
+		cookie, ok4 := readField()
+
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			break
+		}
+
+		if string(name) != cookieName || (len(entryNumber) > 0 && string(entryNumber) != number) {
+			continue
+		}
+
+		if family == xauthFamilyWild || (local && family == xauthFamilyLocal && string(address) == hostname) || (!local && family != xauthFamilyLocal) {
+			return cookieName, cookie
+		}
+
+	}
+
+	return "", nil
+
+}
@@ -0,0 +1,363 @@
+package clipboard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+// fakeXServer stands in for an X server at the other end of a selectionOwner's connection, so the requests it makes
+// can be checked.
+type fakeXServer struct {
+	t      *testing.T
+	conn   net.Conn
+	atoms  map[string]uint32
+	names  map[uint32]string
+	owner  uint32 // The window that owns the clipboard
+	window uint32 // The window that was made
+}
+
+const fakeRequestor = 0x600001
+
+// newFakeSelectionOwner makes a selectionOwner offering the items, connected to a fakeXServer with the maximum
+// request length given.
+func newFakeSelectionOwner(t *testing.T, maxRequestLength uint16, items []Item) (*selectionOwner, *fakeXServer) {
+
+	client, server := net.Pipe()
+
+	fake := &fakeXServer{t: t, conn: server, atoms: map[string]uint32{}, names: map[uint32]string{}}
+
+	done := make(chan struct{})
+
+	go func() {
+		fake.acceptSetup(maxRequestLength)
+		close(done)
+	}()
+
+	owner, err := newSelectionOwner(client, "", nil, items)
+	if err != nil {
+		t.Fatalf("newSelectionOwner() returned an error: %s", err)
+	}
+
+	<-done
+
+	if fake.owner == 0 || fake.owner != fake.window {
+		t.Fatalf("the clipboard is owned by window %x, want the window that was made (%x)", fake.owner, fake.window)
+	}
+
+	return owner, fake
+
+}
+
+// acceptSetup answers the connection setup and the requests the selectionOwner makes until it owns the clipboard.
+func (fake *fakeXServer) acceptSetup(maxRequestLength uint16) {
+
+	setup := make([]byte, 12)
+	if _, err := io.ReadFull(fake.conn, setup); err != nil {
+		fake.t.Error(err)
+		return
+	}
+
+	if setup[0] != 'l' {
+		fake.t.Errorf("got byte order %q, want little endian", setup[0])
+	}
+
+	data := make([]byte, 32+40)                       // No vendor or formats, and a single screen
+	binary.LittleEndian.PutUint32(data[4:], 0x400000) // Resource ID base
+	binary.LittleEndian.PutUint32(data[8:], 0x1fffff) // Resource ID mask
+	binary.LittleEndian.PutUint16(data[18:], maxRequestLength)
+	data[20] = 1                                       // Screens
+	binary.LittleEndian.PutUint32(data[32:], 0x000100) // Root window
+
+	header := []byte{1, 0, 11, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(header[6:], uint16(len(data)/4))
+
+	fake.conn.Write(append(header, data...))
+
+	for fake.owner == 0 {
+
+		opcode, body := fake.readRequest()
+
+		switch opcode {
+
+		case x11InternAtom:
+
+			name := string(body[4 : 4+binary.LittleEndian.Uint16(body)])
+
+			if _, exists := fake.atoms[name]; !exists {
+				atom := uint32(100 + len(fake.atoms))
+				fake.atoms[name] = atom
+				fake.names[atom] = name
+			}
+
+			reply := make([]byte, 32)
+			reply[0] = x11Reply
+			binary.LittleEndian.PutUint32(reply[8:], fake.atoms[name])
+			fake.conn.Write(reply)
+
+		case x11CreateWindow:
+			fake.window = binary.LittleEndian.Uint32(body)
+
+		case x11SetSelectionOwner:
+			if binary.LittleEndian.Uint32(body[4:]) == fake.atoms["CLIPBOARD"] {
+				fake.owner = binary.LittleEndian.Uint32(body)
+			}
+
+		default:
+			fake.t.Errorf("unexpected request %d while setting up", opcode)
+			return
+
+		}
+
+	}
+
+}
+
+// readRequest reads the next request, returning its opcode and everything after its length.
+func (fake *fakeXServer) readRequest() (byte, []byte) {
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(fake.conn, header); err != nil {
+		fake.t.Fatalf("could not read request: %s", err)
+	}
+
+	body := make([]byte, int(binary.LittleEndian.Uint16(header[2:]))*4-4)
+	if _, err := io.ReadFull(fake.conn, body); err != nil {
+		fake.t.Fatalf("could not read request: %s", err)
+	}
+
+	return header[0], body
+
+}
+
+// expect reads the next request, failing if it isn't the one expected.
+func (fake *fakeXServer) expect(opcode byte) []byte {
+
+	got, body := fake.readRequest()
+	if got != opcode {
+		fake.t.Fatalf("got request %d, want %d", got, opcode)
+	}
+
+	return body
+
+}
+
+// expectProperty reads a ChangeProperty request, returning the property's type and data.
+func (fake *fakeXServer) expectProperty(property uint32) (string, []byte) {
+
+	body := fake.expect(x11ChangeProperty)
+
+	if window := binary.LittleEndian.Uint32(body); window != fakeRequestor {
+		fake.t.Errorf("property set on window %x, want the requestor's", window)
+	}
+
+	if got := binary.LittleEndian.Uint32(body[4:]); got != property {
+		fake.t.Errorf("got property %s, want %s", fake.names[got], fake.names[property])
+	}
+
+	format := int(body[12])
+	length := int(binary.LittleEndian.Uint32(body[16:])) * format / 8
+
+	return fake.names[binary.LittleEndian.Uint32(body[8:])], body[20 : 20+length]
+
+}
+
+// expectNotify reads the SelectionNotify sent to the requestor, returning the property the data was put in.
+func (fake *fakeXServer) expectNotify() uint32 {
+
+	body := fake.expect(x11SendEvent)
+	event := body[8:]
+
+	if event[0] != x11SelectionNotify || binary.LittleEndian.Uint32(event[8:]) != fakeRequestor {
+		fake.t.Fatalf("sent %v, want a SelectionNotify to the requestor", event)
+	}
+
+	return binary.LittleEndian.Uint32(event[20:])
+
+}
+
+// request asks for the clipboard's contents as the target, to be put in the property.
+func (fake *fakeXServer) request(target, property string) {
+
+	event := make([]byte, 32)
+	event[0] = x11SelectionRequest
+	binary.LittleEndian.PutUint32(event[8:], fake.owner)
+	binary.LittleEndian.PutUint32(event[12:], fakeRequestor)
+	binary.LittleEndian.PutUint32(event[16:], fake.atoms["CLIPBOARD"])
+	binary.LittleEndian.PutUint32(event[20:], fake.atom(target))
+	binary.LittleEndian.PutUint32(event[24:], fake.atom(property))
+
+	fake.conn.Write(event)
+
+}
+
+// atom returns the atom for the name, making one up if the selectionOwner hasn't asked for it.
+func (fake *fakeXServer) atom(name string) uint32 {
+
+	if _, exists := fake.atoms[name]; !exists {
+		atom := uint32(100 + len(fake.atoms))
+		fake.atoms[name] = atom
+		fake.names[atom] = name
+	}
+
+	return fake.atoms[name]
+
+}
+
+func TestSelectionOwnerOffersEveryItem(t *testing.T) {
+
+	items := []Item{{TasksTarget, []byte(`{"Tasks": []}`)}, {"image/png", []byte("PNG")}, {"text/plain", []byte("Buy more coffee")}}
+
+	owner, fake := newFakeSelectionOwner(t, 65535, items)
+	defer owner.Close()
+
+	fake.request("TARGETS", "CLIP_TEMPORARY")
+
+	propertyType, data := fake.expectProperty(fake.atoms["CLIP_TEMPORARY"])
+
+	if propertyType != "ATOM" {
+		t.Errorf("TARGETS given as %s, want ATOM", propertyType)
+	}
+
+	targets := []string{}
+	for i := 0; i < len(data); i += 4 {
+		targets = append(targets, fake.names[binary.LittleEndian.Uint32(data[i:])])
+	}
+
+	for _, target := range []string{"TARGETS", TasksTarget, "image/png", "text/plain", "UTF8_STRING", "STRING"} {
+		if !ContainsTarget(targets, target) {
+			t.Errorf("%s isn't among the targets offered (%v)", target, targets)
+		}
+	}
+
+	if property := fake.expectNotify(); property != fake.atoms["CLIP_TEMPORARY"] {
+		t.Errorf("got notified of property %s, want CLIP_TEMPORARY", fake.names[property])
+	}
+
+	for _, want := range []struct{ Target, Type, Data string }{
+		{TasksTarget, TasksTarget, `{"Tasks": []}`},
+		{"image/png", "image/png", "PNG"},
+		{"UTF8_STRING", "UTF8_STRING", "Buy more coffee"},
+		{"TEXT", "UTF8_STRING", "Buy more coffee"},
+	} {
+
+		fake.request(want.Target, "CLIP_TEMPORARY")
+
+		propertyType, data := fake.expectProperty(fake.atoms["CLIP_TEMPORARY"])
+
+		if propertyType != want.Type || string(data) != want.Data {
+			t.Errorf("got %q as %s for %s, want %q as %s", data, propertyType, want.Target, want.Data, want.Type)
+		}
+
+		fake.expectNotify()
+
+	}
+
+	// Targets that aren't on the clipboard are refused.
+	fake.request("application/pdf", "CLIP_TEMPORARY")
+
+	if property := fake.expectNotify(); property != 0 {
+		t.Errorf("got notified of property %s for a target that isn't on the clipboard, want none", fake.names[property])
+	}
+
+}
+
+func TestSelectionOwnerSendsLargeDataInChunks(t *testing.T) {
+
+	data := bytes.Repeat([]byte("0123456789"), 10)
+
+	// The maximum request length only leaves room for 40 bytes of data per request.
+	owner, fake := newFakeSelectionOwner(t, 16, []Item{{"image/png", data}})
+	defer owner.Close()
+
+	fake.request("image/png", "CLIP_TEMPORARY")
+
+	if body := fake.expect(x11ChangeWindowAttributes); binary.LittleEndian.Uint32(body) != fakeRequestor {
+		t.Errorf("property changes weren't asked for on the requestor's window")
+	}
+
+	propertyType, size := fake.expectProperty(fake.atoms["CLIP_TEMPORARY"])
+
+	if propertyType != "INCR" || binary.LittleEndian.Uint32(size) != uint32(len(data)) {
+		t.Fatalf("got %v as %s, want the size as INCR", size, propertyType)
+	}
+
+	fake.expectNotify()
+
+	received := []byte{}
+
+	for {
+
+		// Deleting the property asks for the next chunk.
+		event := make([]byte, 32)
+		event[0] = x11PropertyNotify
+		binary.LittleEndian.PutUint32(event[4:], fakeRequestor)
+		binary.LittleEndian.PutUint32(event[8:], fake.atoms["CLIP_TEMPORARY"])
+		event[16] = x11PropertyDeleted
+		fake.conn.Write(event)
+
+		propertyType, chunk := fake.expectProperty(fake.atoms["CLIP_TEMPORARY"])
+
+		if propertyType != "image/png" {
+			t.Errorf("chunk given as %s, want image/png", propertyType)
+		}
+
+		if len(chunk) == 0 {
+			break
+		}
+
+		received = append(received, chunk...)
+
+	}
+
+	if !bytes.Equal(received, data) {
+		t.Errorf("got %q in chunks, want %q", received, data)
+	}
+
+}
+
+func TestSelectionOwnerStopsWhenCleared(t *testing.T) {
+
+	owner, fake := newFakeSelectionOwner(t, 65535, []Item{{"text/plain", []byte("Buy more coffee")}})
+	defer owner.Close()
+
+	event := make([]byte, 32)
+	event[0] = x11SelectionClear
+	binary.LittleEndian.PutUint32(event[8:], fake.owner)
+	binary.LittleEndian.PutUint32(event[12:], fake.atoms["CLIPBOARD"])
+	fake.conn.Write(event)
+
+	// Something else owns the clipboard now, so the connection's closed.
+	if _, err := fake.conn.Read(make([]byte, 1)); err == nil {
+		t.Errorf("the connection's still open after losing the clipboard")
+	}
+
+}
+
+func TestXauthCookie(t *testing.T) {
+
+	entry := func(family uint16, fields ...string) []byte {
+		data := make([]byte, 2)
+		binary.BigEndian.PutUint16(data, family)
+		for _, field := range fields {
+			length := make([]byte, 2)
+			binary.BigEndian.PutUint16(length, uint16(len(field)))
+			data = append(append(data, length...), field...)
+		}
+		return data
+	}
+
+	data := append(entry(xauthFamilyLocal, "desktop", "1", "MIT-MAGIC-COOKIE-1", "wrong display"),
+		entry(xauthFamilyLocal, "desktop", "0", "MIT-MAGIC-COOKIE-1", "cookie")...)
+
+	if name, cookie := xauthCookie(data, "desktop", "0", true); name != "MIT-MAGIC-COOKIE-1" || string(cookie) != "cookie" {
+		t.Errorf("got %s %q, want the cookie for display 0", name, cookie)
+	}
+
+	if name, _ := xauthCookie(data, "laptop", "0", true); name != "" {
+		t.Errorf("got a cookie for another machine")
+	}
+
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
@@ -87,6 +88,51 @@ func FileExists(filepath string) bool {
 	return true
 }
 
+// WriteFileAtomically writes the data to a temporary file beside the destination and then renames it over the
+// destination, so the destination is either left as it was or fully written, never anything in between.
+func WriteFileAtomically(path string, data []byte) error {
+
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tempFile, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return err
+	}
+
+	tempPath := tempFile.Name()
+
+	_, err = tempFile.Write(data)
+	if err == nil {
+		err = tempFile.Sync() // Want to make sure the file is written before it replaces the original
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	// TempFile creates files only readable by the user, so we carry over the original file's permissions
+	mode := os.FileMode(0644)
+	if stats, statErr := os.Stat(path); statErr == nil {
+		mode = stats.Mode()
+	}
+	if err == nil {
+		err = os.Chmod(tempPath, mode)
+	}
+
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+
+	if err != nil {
+		os.Remove(tempPath)
+	}
+
+	return err
+
+}
+
 var mouseInputs = map[int32]int{}
 var hiddenMouseInputs = map[int32]bool{}
 
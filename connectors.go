@@ -0,0 +1,457 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/solarlune/masterplan/model"
+)
+
+// How a Connection's line goes from one Task to the other.
+const (
+	ConnectionRoutingStraight   = "straight"
+	ConnectionRoutingOrthogonal = "orthogonal"
+	ConnectionRoutingCurved     = "curved"
+)
+
+// ConnectionRoutings lists every routing in the order they're presented to the user.
+var ConnectionRoutings = []string{ConnectionRoutingStraight, ConnectionRoutingOrthogonal, ConnectionRoutingCurved}
+
+const (
+	connectionThickness = 2
+	connectionArrowSize = 12
+	connectionLabelSize = 32
+
+	// How many line segments a curved Connection is drawn with.
+	connectionCurveSegments = 24
+)
+
+// A Connection is a line (or arrow) linking two Tasks on the same Board. It's drawn between the Tasks' Rects, so
+// it follows them around as they're dragged.
+type Connection struct {
+	Start, End *Task
+	ArrowStart bool
+	ArrowEnd   bool
+	Label      string
+	Color      rl.Color // A Color with no alpha draws in the theme's font color
+	Routing    string
+}
+
+func NewConnection(start, end *Task) *Connection {
+	return &Connection{
+		Start:    start,
+		End:      end,
+		ArrowEnd: true,
+		Routing:  ConnectionRoutingStraight,
+	}
+}
+
+// Other returns the Task on the other end of the Connection from the one given.
+func (connection *Connection) Other(task *Task) *Task {
+	if connection.Start == task {
+		return connection.End
+	}
+	return connection.Start
+}
+
+// DrawColor returns the color the Connection's drawn in.
+func (connection *Connection) DrawColor() rl.Color {
+	if connection.Color.A == 0 {
+		return getThemeColor(GUI_FONT_COLOR)
+	}
+	return connection.Color
+}
+
+func rectCenter(rect rl.Rectangle) rl.Vector2 {
+	return rl.Vector2{rect.X + rect.Width/2, rect.Y + rect.Height/2}
+}
+
+// rectEdgeToward returns where the line from the center of the rectangle to the target crosses its edge.
+func rectEdgeToward(rect rl.Rectangle, target rl.Vector2) rl.Vector2 {
+
+	center := rectCenter(rect)
+	dx, dy := target.X-center.X, target.Y-center.Y
+
+	if dx == 0 && dy == 0 {
+		return center
+	}
+
+	scale := float32(math.Inf(1))
+
+	if dx != 0 {
+		scale = float32(math.Abs(float64(rect.Width / 2 / dx)))
+	}
+
+	if dy != 0 {
+		if s := float32(math.Abs(float64(rect.Height / 2 / dy))); s < scale {
+			scale = s
+		}
+	}
+
+	return rl.Vector2{center.X + dx*scale, center.Y + dy*scale}
+
+}
+
+// rectSideToward returns the middle of the side of the rectangle facing the target, along with which way that
+// side faces.
+func rectSideToward(rect rl.Rectangle, target rl.Vector2, horizontal bool) (rl.Vector2, rl.Vector2) {
+
+	center := rectCenter(rect)
+
+	if horizontal {
+		if target.X < center.X {
+			return rl.Vector2{rect.X, center.Y}, rl.Vector2{-1, 0}
+		}
+		return rl.Vector2{rect.X + rect.Width, center.Y}, rl.Vector2{1, 0}
+	}
+
+	if target.Y < center.Y {
+		return rl.Vector2{center.X, rect.Y}, rl.Vector2{0, -1}
+	}
+	return rl.Vector2{center.X, rect.Y + rect.Height}, rl.Vector2{0, 1}
+
+}
+
+// Points returns the points the Connection's line goes through, from its start to its end.
+func (connection *Connection) Points() []rl.Vector2 {
+
+	startRect, endRect := connection.Start.Rect, connection.End.Rect
+	startCenter, endCenter := rectCenter(startRect), rectCenter(endRect)
+
+	// Orthogonal and curved lines leave from the sides of the Tasks that face each other the most.
+	horizontal := math.Abs(float64(endCenter.X-startCenter.X)) >= math.Abs(float64(endCenter.Y-startCenter.Y))
+
+	switch connection.Routing {
+
+	case ConnectionRoutingOrthogonal:
+
+		start, _ := rectSideToward(startRect, endCenter, horizontal)
+		end, _ := rectSideToward(endRect, startCenter, horizontal)
+
+		if horizontal {
+			midX := (start.X + end.X) / 2
+			return []rl.Vector2{start, {midX, start.Y}, {midX, end.Y}, end}
+		}
+
+		midY := (start.Y + end.Y) / 2
+		return []rl.Vector2{start, {start.X, midY}, {end.X, midY}, end}
+
+	case ConnectionRoutingCurved:
+
+		start, startNormal := rectSideToward(startRect, endCenter, horizontal)
+		end, endNormal := rectSideToward(endRect, startCenter, horizontal)
+
+		// The control points stick out from the sides the line leaves from, so it bends away from the Tasks.
+		reach := rl.Vector2Distance(start, end) / 2
+		control1 := rl.Vector2Add(start, rl.Vector2Scale(startNormal, reach))
+		control2 := rl.Vector2Add(end, rl.Vector2Scale(endNormal, reach))
+
+		points := make([]rl.Vector2, 0, connectionCurveSegments+1)
+
+		for i := 0; i <= connectionCurveSegments; i++ {
+			t := float32(i) / connectionCurveSegments
+			u := 1 - t
+			points = append(points, rl.Vector2{
+				u*u*u*start.X + 3*u*u*t*control1.X + 3*u*t*t*control2.X + t*t*t*end.X,
+				u*u*u*start.Y + 3*u*u*t*control1.Y + 3*u*t*t*control2.Y + t*t*t*end.Y,
+			})
+		}
+
+		return points
+
+	}
+
+	return []rl.Vector2{rectEdgeToward(startRect, endCenter), rectEdgeToward(endRect, startCenter)}
+
+}
+
+// Bounds returns the rectangle covering both of the Connection's Tasks, which the line is drawn within (give or
+// take the bend of a curve).
+func (connection *Connection) Bounds() rl.Rectangle {
+	return rectUnion(connection.Start.Rect, connection.End.Rect)
+}
+
+func drawArrowhead(tip, from rl.Vector2, color rl.Color) {
+
+	direction := rl.Vector2Subtract(tip, from)
+	length := rl.Vector2Length(direction)
+
+	if length == 0 {
+		return
+	}
+
+	direction = rl.Vector2Scale(direction, 1/length)
+	side := rl.Vector2{-direction.Y, direction.X}
+
+	back := rl.Vector2Subtract(tip, rl.Vector2Scale(direction, connectionArrowSize))
+	left := rl.Vector2Add(back, rl.Vector2Scale(side, connectionArrowSize/2))
+	right := rl.Vector2Subtract(back, rl.Vector2Scale(side, connectionArrowSize/2))
+
+	// raylib only draws triangles whose points go counter-clockwise on screen.
+	if (left.X-tip.X)*(right.Y-tip.Y)-(left.Y-tip.Y)*(right.X-tip.X) > 0 {
+		left, right = right, left
+	}
+
+	rl.DrawTriangle(tip, left, right, color)
+
+}
+
+// Draw draws the Connection in world space.
+func (connection *Connection) Draw() {
+
+	points := connection.Points()
+	color := connection.DrawColor()
+
+	for i := 1; i < len(points); i++ {
+		rl.DrawLineEx(points[i-1], points[i], connectionThickness, color)
+	}
+
+	last := len(points) - 1
+
+	if connection.ArrowStart {
+		drawArrowhead(points[0], points[1], color)
+	}
+
+	if connection.ArrowEnd {
+		drawArrowhead(points[last], points[last-1], color)
+	}
+
+	if connection.Label != "" {
+
+		// The label goes halfway along the line.
+		total := float32(0)
+		for i := 1; i < len(points); i++ {
+			total += rl.Vector2Distance(points[i-1], points[i])
+		}
+
+		mid := points[0]
+		remaining := total / 2
+
+		for i := 1; i < len(points); i++ {
+			length := rl.Vector2Distance(points[i-1], points[i])
+			if length >= remaining {
+				if length > 0 {
+					mid = rl.Vector2Lerp(points[i-1], points[i], remaining/length)
+				}
+				break
+			}
+			remaining -= length
+		}
+
+		size := rl.MeasureTextEx(not_shit_font, connection.Label, connectionLabelSize, spacing)
+		pos := rl.Vector2{mid.X - size.X/2, mid.Y - size.Y/2}
+
+		rl.DrawRectangleRec(rl.Rectangle{pos.X - 4, pos.Y, size.X + 8, size.Y}, getThemeColor(GUI_INSIDE))
+		rl.DrawTextEx(not_shit_font, connection.Label, pos, connectionLabelSize, spacing, color)
+
+	}
+
+}
+
+// Data returns the Connection as it's stored, with its Tasks referred to by ID.
+func (connection *Connection) Data() model.ConnectionData {
+	return model.ConnectionData{
+		Start:      connection.Start.ID,
+		End:        connection.End.ID,
+		ArrowStart: connection.ArrowStart,
+		ArrowEnd:   connection.ArrowEnd,
+		Routing:    connection.Routing,
+		Label:      connection.Label,
+		Color:      [4]uint8{connection.Color.R, connection.Color.G, connection.Color.B, connection.Color.A},
+	}
+}
+
+// Serialize returns the Connection as a JSON object in a string, with its Tasks referred to by ID.
+func (connection *Connection) Serialize() string {
+	return connection.Data().Serialize()
+}
+
+// ConnectionFromData creates a Connection from the data given, looking its Tasks up by ID in the map given. It
+// returns nil if either Task doesn't exist or they're on different Boards.
+func ConnectionFromData(data model.ConnectionData, tasksByID map[int]*Task) *Connection {
+
+	start := tasksByID[data.Start]
+	end := tasksByID[data.End]
+
+	if start == nil || end == nil || start == end || start.Board != end.Board {
+		return nil
+	}
+
+	connection := NewConnection(start, end)
+	connection.applyData(data)
+
+	return connection
+
+}
+
+// applyData applies everything but the Tasks from the data given to the Connection.
+func (connection *Connection) applyData(data model.ConnectionData) {
+
+	connection.ArrowStart = data.ArrowStart
+	connection.ArrowEnd = data.ArrowEnd
+	connection.Label = data.Label
+	connection.Routing = ConnectionRoutingStraight
+	connection.Color = rl.Color{data.Color[0], data.Color[1], data.Color[2], data.Color[3]}
+
+	if data.Routing != "" {
+		connection.Routing = data.Routing
+	}
+
+}
+
+// ConnectionBetween returns the Connection linking the two Tasks in either direction, or nil if there isn't one.
+func (board *Board) ConnectionBetween(a, b *Task) *Connection {
+	for _, connection := range board.Connections {
+		if (connection.Start == a && connection.End == b) || (connection.Start == b && connection.End == a) {
+			return connection
+		}
+	}
+	return nil
+}
+
+// ConnectionsOf returns the Connections the Task is at either end of.
+func (board *Board) ConnectionsOf(task *Task) []*Connection {
+	connections := []*Connection{}
+	for _, connection := range board.Connections {
+		if connection.Start == task || connection.End == task {
+			connections = append(connections, connection)
+		}
+	}
+	return connections
+}
+
+// Connect links the two Tasks, returning the Connection between them (which may have already existed).
+func (board *Board) Connect(start, end *Task) *Connection {
+
+	if existing := board.ConnectionBetween(start, end); existing != nil {
+		return existing
+	}
+
+	connection := NewConnection(start, end)
+	board.Connections = append(board.Connections, connection)
+	board.Project.UndoHistory.CaptureConnection(connection)
+	board.Project.MarkModified()
+
+	return connection
+
+}
+
+// Disconnect removes the Connection from the Board.
+func (board *Board) Disconnect(connection *Connection) {
+
+	for i, c := range board.Connections {
+		if c == connection {
+			board.Connections = append(board.Connections[:i], board.Connections[i+1:]...)
+			board.Project.UndoHistory.CaptureConnection(connection)
+			board.Project.MarkModified()
+			return
+		}
+	}
+
+}
+
+// ConnectSelectedTasks connects the selected Tasks one after another, top to bottom and left to right. If they're
+// all already connected that way, the Connections are removed instead.
+func (board *Board) ConnectSelectedTasks() {
+
+	selected := board.SelectedTasks(false)
+
+	if len(selected) < 2 {
+		board.Project.Log("Select at least two Tasks to connect.")
+		return
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].Position.Y == selected[j].Position.Y {
+			return selected[i].Position.X < selected[j].Position.X
+		}
+		return selected[i].Position.Y < selected[j].Position.Y
+	})
+
+	existing := []*Connection{}
+
+	for i := 1; i < len(selected); i++ {
+		if connection := board.ConnectionBetween(selected[i-1], selected[i]); connection != nil {
+			existing = append(existing, connection)
+		}
+	}
+
+	if len(existing) == len(selected)-1 {
+
+		for _, connection := range existing {
+			board.Disconnect(connection)
+		}
+
+		board.Project.Log("Disconnected %d Tasks.", len(selected))
+		return
+
+	}
+
+	for i := 1; i < len(selected); i++ {
+		board.Connect(selected[i-1], selected[i])
+	}
+
+	board.Project.Log("Connected %d Tasks.", len(selected))
+
+}
+
+// removeConnectionsOf takes the Task's Connections off of the Board, keeping them on the Task so they can come back
+// if the Task's deletion is undone.
+func (board *Board) removeConnectionsOf(task *Task) {
+
+	remaining := []*Connection{}
+
+	for _, connection := range board.Connections {
+		if connection.Start == task || connection.End == task {
+			task.detachedConnections = append(task.detachedConnections, connection)
+		} else {
+			remaining = append(remaining, connection)
+		}
+	}
+
+	board.Connections = remaining
+
+}
+
+// restoreConnectionsOf puts back the Connections removed with the Task whose other ends are still on the Board.
+func (board *Board) restoreConnectionsOf(task *Task) {
+
+	for _, connection := range task.detachedConnections {
+
+		other := connection.Other(task)
+
+		for _, t := range board.Tasks {
+			if t == other {
+				if board.ConnectionBetween(connection.Start, connection.End) == nil {
+					board.Connections = append(board.Connections, connection)
+				}
+				break
+			}
+		}
+
+	}
+
+	task.detachedConnections = nil
+
+}
+
+// DrawConnections draws the Board's Connections that are in view; they're drawn before the Tasks so they go
+// underneath them.
+func (board *Board) DrawConnections() {
+
+	view := rlRect(board.Project.Viewport.VisibleRect())
+
+	for _, connection := range board.Connections {
+		if rl.CheckCollisionRecs(connection.Bounds(), view) {
+			connection.Draw()
+		}
+	}
+
+}
+
+// connectionLabel returns how a Task's referred to in the Connection list of the Task editor.
+func connectionLabel(task *Task) string {
+	return fmt.Sprintf("%s #%d", task.TaskType, task.ID)
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/inkyblackness/imgui-go/v3"
+	"github.com/ncruces/zenity"
+)
+
+// What can be exported.
+const (
+	EXPORT_BOARD      = "board"
+	EXPORT_SELECTION  = "selection"
+	EXPORT_ALL_BOARDS = "all boards"
+)
+
+// Exports are rendered a tile at a time, so they aren't limited by the size of the window (or by how big a texture
+// can be); every graphics card can handle render textures this size.
+const exportTileSize = 2048
+
+// Exports bigger than this would take more memory than it's reasonable to ask for, so a smaller scale has to be used.
+const (
+	exportMaxDimension = 32768
+	exportMaxPixels    = 1 << 28
+)
+
+// Exporter holds the state of the PNG export window, and renders exports.
+type Exporter struct {
+	Project    *Project
+	Open       bool
+	Scope      string
+	Scale      float32
+	Background bool // Whether the board's background is drawn, rather than being left transparent
+	Grid       bool
+	Padding    int32 // Space left around the Tasks, in world units
+
+	// Exports have to be rendered outside of the camera's 2D mode, so they're held until then (see HandlePending()).
+	pending []exportJob
+}
+
+type exportJob struct {
+	Board    *Board
+	Tasks    []*Task
+	FilePath string
+}
+
+func NewExporter(project *Project) *Exporter {
+	return &Exporter{
+		Project:    project,
+		Scope:      EXPORT_BOARD,
+		Scale:      1,
+		Background: true,
+		Padding:    32,
+	}
+}
+
+// Draw draws the export window.
+func (exporter *Exporter) Draw() {
+
+	if !exporter.Open {
+		return
+	}
+
+	project := exporter.Project
+
+	if imgui.BeginV("Export PNG", &exporter.Open, imgui.WindowFlagsAlwaysAutoResize) {
+
+		if imgui.RadioButton("Current Board", exporter.Scope == EXPORT_BOARD) {
+			exporter.Scope = EXPORT_BOARD
+		}
+
+		if imgui.RadioButton("Selected Tasks", exporter.Scope == EXPORT_SELECTION) {
+			exporter.Scope = EXPORT_SELECTION
+		}
+
+		if imgui.RadioButton("All Boards (a file for each)", exporter.Scope == EXPORT_ALL_BOARDS) {
+			exporter.Scope = EXPORT_ALL_BOARDS
+		}
+
+		imgui.Separator()
+
+		imgui.SliderFloatV("Scale", &exporter.Scale, 0.25, 8, "%.2fx", 1)
+
+		if imgui.InputInt("Padding", &exporter.Padding) && exporter.Padding < 0 {
+			exporter.Padding = 0
+		}
+
+		imgui.Checkbox("Background", &exporter.Background)
+		imgui.Checkbox("Grid", &exporter.Grid)
+
+		board := project.CurrentBoard()
+
+		if tasks := exporter.tasksFor(board); len(tasks) > 0 {
+			bounds := exporter.bounds(tasks)
+			imgui.Text(fmt.Sprintf("%d x %d pixels", exporter.pixelSize(bounds.Width), exporter.pixelSize(bounds.Height)))
+		}
+
+		imgui.Separator()
+
+		if imgui.Button("Export...") {
+			exporter.Export()
+		}
+
+	}
+
+	imgui.End()
+
+}
+
+// Export asks where to export to, and queues up exporting there.
+func (exporter *Exporter) Export() {
+
+	project := exporter.Project
+
+	boards := []*Board{project.CurrentBoard()}
+	if exporter.Scope == EXPORT_ALL_BOARDS {
+		boards = project.Boards
+	}
+
+	filePath, err := zenity.SelectFileSave(
+		zenity.Title("Select a location and name to export the PNG to."),
+		zenity.ConfirmOverwrite(),
+		zenity.FileFilters{{Name: "PNG image", Patterns: []string{"*.png"}}})
+
+	if err != nil || filePath == "" {
+		return
+	}
+
+	if !strings.HasSuffix(strings.ToLower(filePath), ".png") {
+		filePath += ".png"
+	}
+
+	for _, board := range boards {
+
+		tasks := exporter.tasksFor(board)
+
+		if len(tasks) == 0 {
+			if exporter.Scope != EXPORT_ALL_BOARDS {
+				project.Log("There's nothing to export.")
+			}
+			continue
+		}
+
+		boardPath := filePath
+
+		// Each Board gets its own file, named after it.
+		if exporter.Scope == EXPORT_ALL_BOARDS {
+			ext := filepath.Ext(filePath)
+			boardPath = fmt.Sprintf("%s - %s%s", strings.TrimSuffix(filePath, ext), sanitizeFileName(board.Name), ext)
+		}
+
+		exporter.pending = append(exporter.pending, exportJob{board, tasks, boardPath})
+
+	}
+
+}
+
+// sanitizeFileName replaces characters that can't be in file names on some systems.
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// HandlePending renders the exports that are waiting; it has to be called outside of the camera's 2D mode.
+func (exporter *Exporter) HandlePending() {
+
+	for _, job := range exporter.pending {
+		if err := exporter.ExportPNG(job.Board, job.Tasks, job.FilePath); err != nil {
+			exporter.Project.Log("Could not export [%s]: %s", job.FilePath, err.Error())
+		} else {
+			exporter.Project.Log("Exported [%s].", job.FilePath)
+		}
+	}
+
+	exporter.pending = nil
+
+}
+
+// tasksFor returns the Tasks on the Board to be exported.
+func (exporter *Exporter) tasksFor(board *Board) []*Task {
+	if exporter.Scope == EXPORT_SELECTION {
+		return board.SelectedTasks(false)
+	}
+	return append([]*Task{}, board.Tasks...)
+}
+
+// bounds returns the area the Tasks take up, along with the padding around them, in world units.
+func (exporter *Exporter) bounds(tasks []*Task) rl.Rectangle {
+
+	bounds := rl.Rectangle{}
+
+	for i, task := range tasks {
+		rect := rl.Rectangle{task.Position.X, task.Position.Y, task.Rect.Width, task.Rect.Height}
+		if i == 0 {
+			bounds = rect
+		} else {
+			bounds = unionRect(bounds, rect)
+		}
+	}
+
+	padding := float32(exporter.Padding)
+
+	return rl.Rectangle{bounds.X - padding, bounds.Y - padding, bounds.Width + padding*2, bounds.Height + padding*2}
+
+}
+
+func unionRect(a, b rl.Rectangle) rl.Rectangle {
+	x := float32(math.Min(float64(a.X), float64(b.X)))
+	y := float32(math.Min(float64(a.Y), float64(b.Y)))
+	right := float32(math.Max(float64(a.X+a.Width), float64(b.X+b.Width)))
+	bottom := float32(math.Max(float64(a.Y+a.Height), float64(b.Y+b.Height)))
+	return rl.Rectangle{x, y, right - x, bottom - y}
+}
+
+// pixelSize returns how many pixels the length in world units comes out to in the export.
+func (exporter *Exporter) pixelSize(length float32) int {
+	return int(math.Ceil(float64(length * exporter.Scale)))
+}
+
+// ExportPNG renders the Tasks (and the Connections between them) into a PNG at the file path given. It's rendered
+// tile by tile into an offscreen texture, with each tile being read back and pieced into the whole image.
+func (exporter *Exporter) ExportPNG(board *Board, tasks []*Task, filePath string) error {
+
+	bounds := exporter.bounds(tasks)
+	scale := exporter.Scale
+
+	width := exporter.pixelSize(bounds.Width)
+	height := exporter.pixelSize(bounds.Height)
+
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("there's nothing to export")
+	}
+
+	if width > exportMaxDimension || height > exportMaxDimension || width*height > exportMaxPixels {
+		return fmt.Errorf("the image would be %d x %d pixels, which is too big; try a smaller scale", width, height)
+	}
+
+	output := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	tile := rl.LoadRenderTexture(exportTileSize, exportTileSize)
+	defer rl.UnloadRenderTexture(tile)
+
+	exported := map[*Task]bool{}
+
+	sorted := append([]*Task{}, tasks...)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Depth() == sorted[j].Depth() {
+			if sorted[i].Position.Y == sorted[j].Position.Y {
+				return sorted[i].Position.X < sorted[j].Position.X
+			}
+			return sorted[i].Position.Y < sorted[j].Position.Y
+		}
+		return sorted[i].Depth() < sorted[j].Depth()
+	})
+
+	// Tasks skip drawing when they're off screen and draw handles when they're selected, so everything being exported
+	// is drawn as if it's visible and unselected, and put back the way it was afterwards. Tasks are drawn where they're
+	// headed, not partway through sliding there.
+	type taskState struct {
+		Visible, Selected bool
+		Rect              rl.Rectangle
+	}
+
+	states := map[*Task]taskState{}
+
+	for _, task := range sorted {
+		states[task] = taskState{task.Visible, task.Selected, task.Rect}
+		exported[task] = true
+		task.Visible = true
+		task.Selected = false
+		task.Rect.X, task.Rect.Y = task.Position.X, task.Position.Y
+	}
+
+	defer func() {
+		for task, state := range states {
+			task.Visible = state.Visible
+			task.Selected = state.Selected
+			task.Rect.X, task.Rect.Y = state.Rect.X, state.Rect.Y
+		}
+	}()
+
+	background := rl.Color{}
+	if exporter.Background {
+		background = getThemeColor(GUI_INSIDE_DISABLED)
+	}
+
+	for tileY := 0; tileY < height; tileY += exportTileSize {
+
+		for tileX := 0; tileX < width; tileX += exportTileSize {
+
+			view := rl.Rectangle{
+				bounds.X + float32(tileX)/scale,
+				bounds.Y + float32(tileY)/scale,
+				exportTileSize / scale,
+				exportTileSize / scale,
+			}
+
+			rl.BeginTextureMode(tile)
+			rl.ClearBackground(background)
+			rl.BeginMode2D(rl.Camera2D{Target: rl.Vector2{view.X, view.Y}, Zoom: scale})
+
+			if exporter.Grid {
+				exporter.drawGrid(board, view)
+			}
+
+			for _, connection := range board.Connections {
+				if exported[connection.Start] && exported[connection.End] && rl.CheckCollisionRecs(connection.Bounds(), view) {
+					connection.Draw()
+				}
+			}
+
+			for _, task := range sorted {
+				if rl.CheckCollisionRecs(task.Rect, view) {
+					task.Draw()
+				}
+			}
+
+			rl.EndMode2D()
+			rl.EndTextureMode()
+
+			data := rl.GetTextureData(tile.Texture)
+			tileImage := data.ToImage()
+			rl.UnloadImage(data)
+
+			copyExportTile(output, tileImage, tileX, tileY)
+
+		}
+
+	}
+
+	buffer := bytes.Buffer{}
+
+	if err := png.Encode(&buffer, output); err != nil {
+		return err
+	}
+
+	return WriteFileAtomically(filePath, buffer.Bytes())
+
+}
+
+// copyExportTile copies the tile rendered into the output image at the position given. Render textures come back
+// upside down, so the tile's flipped as it's copied.
+func copyExportTile(output *image.RGBA, tile image.Image, x, y int) {
+
+	tileBounds := tile.Bounds()
+	tileRGBA, isRGBA := tile.(*image.RGBA)
+
+	for row := 0; row < tileBounds.Dy() && y+row < output.Rect.Dy(); row++ {
+
+		sourceRow := tileBounds.Dy() - 1 - row
+		columns := tileBounds.Dx()
+		if x+columns > output.Rect.Dx() {
+			columns = output.Rect.Dx() - x
+		}
+
+		if isRGBA {
+			source := tileRGBA.Pix[sourceRow*tileRGBA.Stride : sourceRow*tileRGBA.Stride+columns*4]
+			copy(output.Pix[(y+row)*output.Stride+x*4:], source)
+		} else {
+			for column := 0; column < columns; column++ {
+				output.Set(x+column, y+row, tile.At(tileBounds.Min.X+column, tileBounds.Min.Y+sourceRow))
+			}
+		}
+
+	}
+
+}
+
+// drawGrid draws the Board's grid lines across the view given.
+func (exporter *Exporter) drawGrid(board *Board, view rl.Rectangle) {
+
+	gs := float32(board.Project.GridSize)
+	color := getThemeColor(GUI_INSIDE)
+	thickness := 1 / exporter.Scale // A pixel wide, whatever the scale
+
+	for x := float32(math.Floor(float64(view.X/gs))) * gs; x <= view.X+view.Width; x += gs {
+		rl.DrawLineEx(rl.Vector2{x, view.Y}, rl.Vector2{x, view.Y + view.Height}, thickness, color)
+	}
+
+	for y := float32(math.Floor(float64(view.Y/gs))) * gs; y <= view.Y+view.Height; y += gs {
+		rl.DrawLineEx(rl.Vector2{view.X, y}, rl.Vector2{view.X + view.Width, y}, thickness, color)
+	}
+
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+	"log"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// GIFs asking for a delay this short or shorter get played at the default delay instead, like browsers do.
+const (
+	gifMinimumDelay = 1
+	gifDefaultDelay = 0.1
+)
+
+// Each frame is its own texture the size of the whole GIF, so GIFs with more pixels than this across all of their
+// frames (128MB worth of textures) only show their first frame, rather than filling up the GPU's memory.
+const gifMaxPixels = 32 * 1024 * 1024
+
+// GifAnimation is an animated GIF with each frame uploaded as a texture, ready to be played back. The frames are
+// composited the way the GIF describes, so each texture is the whole image as it's shown at that point.
+type GifAnimation struct {
+	Frames []rl.Texture2D
+	Delays []float32 // How long each frame is shown for, in seconds
+	Width  int32
+	Height int32
+}
+
+// DecodedGif is a GIF with its frames composited, but not uploaded as textures yet. Decoding can be done in the
+// background, while uploading has to be done on the main thread.
+type DecodedGif struct {
+	Frames []*rl.Image
+	Delays []float32
+	Width  int32
+	Height int32
+}
+
+func DecodeGif(data *gif.GIF) *DecodedGif {
+
+	bounds := image.Rect(0, 0, data.Config.Width, data.Config.Height)
+
+	// Some GIFs don't fill out the logical screen size, so we fall back to the area the frames cover.
+	if bounds.Empty() {
+		for _, frame := range data.Image {
+			bounds = bounds.Union(frame.Bounds())
+		}
+	}
+
+	decoded := &DecodedGif{
+		Frames: []*rl.Image{},
+		Delays: []float32{},
+		Width:  int32(bounds.Dx()),
+		Height: int32(bounds.Dy()),
+	}
+
+	frames := data.Image
+
+	if len(frames) > 1 && bounds.Dx()*bounds.Dy()*len(frames) > gifMaxPixels {
+		log.Printf("GIF is too large to animate (%d frames at %dx%d); only its first frame is shown.", len(frames), bounds.Dx(), bounds.Dy())
+		frames = frames[:1]
+	}
+
+	canvas := image.NewRGBA(bounds)
+
+	for i, frame := range frames {
+
+		disposal := byte(0)
+		if i < len(data.Disposal) {
+			disposal = data.Disposal[i]
+		}
+
+		var previous *image.RGBA
+
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		decoded.Frames = append(decoded.Frames, rl.NewImageFromImage(canvas))
+
+		delay := float32(gifDefaultDelay)
+		if i < len(data.Delay) && data.Delay[i] > gifMinimumDelay {
+			delay = float32(data.Delay[i]) / 100 // GIF delays are in hundredths of a second
+		}
+		decoded.Delays = append(decoded.Delays, delay)
+
+		// Disposal says what happens to the frame's area before the next frame is drawn over it.
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+
+	}
+
+	return decoded
+
+}
+
+// Upload turns the decoded frames into textures for a GifAnimation, freeing the frames' images afterwards.
+func (decoded *DecodedGif) Upload() *GifAnimation {
+
+	animation := &GifAnimation{
+		Frames: []rl.Texture2D{},
+		Delays: decoded.Delays,
+		Width:  decoded.Width,
+		Height: decoded.Height,
+	}
+
+	for _, frame := range decoded.Frames {
+		animation.Frames = append(animation.Frames, rl.LoadTextureFromImage(frame))
+	}
+
+	decoded.Free()
+
+	return animation
+
+}
+
+// Free frees the frames' images without uploading them.
+func (decoded *DecodedGif) Free() {
+	for _, frame := range decoded.Frames {
+		rl.UnloadImage(frame)
+	}
+	decoded.Frames = []*rl.Image{}
+}
+
+// Duration returns how long the animation takes to play through once, in seconds.
+func (animation *GifAnimation) Duration() float32 {
+	duration := float32(0)
+	for _, delay := range animation.Delays {
+		duration += delay
+	}
+	return duration
+}
+
+// FrameAt returns the index of the frame shown at the time given, looping the animation.
+func (animation *GifAnimation) FrameAt(time float32) int {
+
+	duration := animation.Duration()
+
+	if duration <= 0 || len(animation.Frames) == 0 {
+		return 0
+	}
+
+	time = float32(math.Mod(float64(time), float64(duration)))
+
+	for i, delay := range animation.Delays {
+		if time < delay {
+			return i
+		}
+		time -= delay
+	}
+
+	return len(animation.Frames) - 1
+
+}
+
+func (animation *GifAnimation) Destroy() {
+	for _, frame := range animation.Frames {
+		rl.UnloadTexture(frame)
+	}
+	animation.Frames = []rl.Texture2D{}
+}
+
+// animationsFrozen returns whether animations should hold still, which they do while the window's unfocused if the
+// setting to save CPU then is on.
+func animationsFrozen() bool {
+	return programSettings.PauseAnimationsUnfocused && !rl.IsWindowFocused()
+}
@@ -0,0 +1,329 @@
+// Package httpcache keeps copies of downloaded files on disk, revalidating them with the server they came from.
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// The suffix of the files the cache's entries are kept in. It can't just be ".json", as that's also what a
+// downloaded JSON file would be named.
+const entrySuffix = ".meta.json"
+
+// cacheEntry is what's known about a cached download; it's kept beside the downloaded file as JSON.
+type cacheEntry struct {
+	URL          string
+	File         string // The downloaded file's name within the cache directory
+	ETag         string
+	LastModified string
+	Size         int64
+	LastUsed     time.Time
+}
+
+// Cache keeps copies of downloaded resources on disk, so remote images don't have to be downloaded again every
+// time a project is opened, and still show when the network can't be reached. Files are named after a hash of their
+// URL; cached copies are revalidated with the server using their ETag and Last-Modified headers, and the least
+// recently used copies are thrown out once the cache grows past its maximum size. It's safe to fetch from more than
+// one goroutine at a time; files that have been fetched are kept until they're released (see Release()), so
+// they're not thrown out from under whatever's reading them.
+type Cache struct {
+	Directory string
+	Client    *http.Client
+
+	mutex   sync.Mutex
+	maxSize int64          // In bytes; 0 or less means the cache can grow without limit
+	inUse   map[string]int // How many times each entry's been fetched without being released, by key
+}
+
+func New(directory string, maxSize int64) *Cache {
+	return &Cache{
+		Directory: directory,
+		Client:    &http.Client{Timeout: 15 * time.Second},
+		maxSize:   maxSize,
+		inUse:     map[string]int{},
+	}
+}
+
+// SetMaxSize sets how large the cache can grow before the least recently used files are thrown out, in bytes; 0 or
+// less means it can grow without limit.
+func (cache *Cache) SetMaxSize(maxSize int64) {
+	cache.mutex.Lock()
+	cache.maxSize = maxSize
+	cache.mutex.Unlock()
+}
+
+func (cache *Cache) key(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(hash[:])
+}
+
+func (cache *Cache) entryPath(key string) string {
+	return filepath.Join(cache.Directory, key+entrySuffix)
+}
+
+func (cache *Cache) loadEntry(key string) *cacheEntry {
+
+	data, err := ioutil.ReadFile(cache.entryPath(key))
+	if err != nil {
+		return nil
+	}
+
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(data, entry); err != nil || entry.File == "" {
+		return nil
+	}
+
+	// The entry's no good if the file it's for has gone missing.
+	if _, err := os.Stat(filepath.Join(cache.Directory, entry.File)); err != nil {
+		return nil
+	}
+
+	return entry
+
+}
+
+func (cache *Cache) saveEntry(key string, entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(cache.entryPath(key), data)
+}
+
+// Fetch returns the path to a local copy of the file at the URL, downloading it if it isn't cached or has changed
+// on the server. If the server can't be reached, the cached copy is returned as it is, if there is one. The file
+// won't be evicted until it's released with Release().
+func (cache *Cache) Fetch(url string) (string, error) {
+
+	key := cache.key(url)
+
+	cache.mutex.Lock()
+	cache.inUse[key]++
+	cache.mutex.Unlock()
+
+	path, err := cache.fetch(key, url)
+
+	if err != nil {
+		cache.Release(url)
+	}
+
+	return path, err
+
+}
+
+// Release lets the file fetched from the URL be evicted again, once whatever fetched it is done reading it.
+func (cache *Cache) Release(url string) {
+
+	key := cache.key(url)
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.inUse[key] <= 1 {
+		delete(cache.inUse, key)
+	} else {
+		cache.inUse[key]--
+	}
+
+}
+
+func (cache *Cache) fetch(key, url string) (string, error) {
+
+	entry := cache.loadEntry(key)
+
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if entry != nil {
+		if entry.ETag != "" {
+			request.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			request.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	response, err := cache.Client.Do(request)
+
+	if err != nil {
+		if entry != nil {
+			return cache.use(key, entry), nil // Offline, so the cached copy will have to do
+		}
+		return "", err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified && entry != nil {
+		return cache.use(key, entry), nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		if entry != nil && response.StatusCode >= 500 {
+			return cache.use(key, entry), nil
+		}
+		return "", fmt.Errorf("server responded with %s", response.Status)
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		if entry != nil {
+			return cache.use(key, entry), nil
+		}
+		return "", err
+	}
+
+	if err := os.MkdirAll(cache.Directory, 0755); err != nil {
+		return "", err
+	}
+
+	// raylib goes by file extensions to know how to load files, so the file's named for what it actually is.
+	fileName := key + mimetype.Detect(data).Extension()
+
+	if err := writeFileAtomically(filepath.Join(cache.Directory, fileName), data); err != nil {
+		return "", err
+	}
+
+	// The file's type may have changed, leaving the old copy under another name.
+	if entry != nil && entry.File != fileName {
+		os.Remove(filepath.Join(cache.Directory, entry.File))
+	}
+
+	entry = &cacheEntry{
+		URL:          url,
+		File:         fileName,
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+		Size:         int64(len(data)),
+		LastUsed:     time.Now(),
+	}
+
+	if err := cache.saveEntry(key, entry); err != nil {
+		return "", err
+	}
+
+	cache.Evict()
+
+	return filepath.Join(cache.Directory, fileName), nil
+
+}
+
+// use marks the entry as just used, so it's the last to be evicted, and returns the path to its file.
+func (cache *Cache) use(key string, entry *cacheEntry) string {
+	entry.LastUsed = time.Now()
+	cache.saveEntry(key, entry)
+	return filepath.Join(cache.Directory, entry.File)
+}
+
+// Evict removes the least recently used files until the cache fits within its maximum size, skipping the ones that
+// are in use (having been fetched, but not released yet).
+func (cache *Cache) Evict() {
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.maxSize <= 0 {
+		return
+	}
+
+	files, err := ioutil.ReadDir(cache.Directory)
+	if err != nil {
+		return
+	}
+
+	type cached struct {
+		Key   string
+		Entry *cacheEntry
+	}
+
+	entries := []cached{}
+	total := int64(0)
+
+	for _, file := range files {
+
+		if !strings.HasSuffix(file.Name(), entrySuffix) {
+			continue
+		}
+
+		key := strings.TrimSuffix(file.Name(), entrySuffix)
+
+		if entry := cache.loadEntry(key); entry != nil {
+			entries = append(entries, cached{key, entry})
+			total += entry.Size
+		}
+
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Entry.LastUsed.Before(entries[j].Entry.LastUsed) })
+
+	for _, c := range entries {
+
+		if total <= cache.maxSize {
+			break
+		}
+
+		if cache.inUse[c.Key] > 0 {
+			continue
+		}
+
+		os.Remove(filepath.Join(cache.Directory, c.Entry.File))
+		os.Remove(cache.entryPath(c.Key))
+		total -= c.Entry.Size
+
+	}
+
+}
+
+// Clear removes everything from the cache.
+func (cache *Cache) Clear() error {
+	return os.RemoveAll(cache.Directory)
+}
+
+// writeFileAtomically writes the file by writing a temporary file next to it and moving that over it, so the file
+// is never left half-written (like the main package's WriteFileAtomically()).
+func writeFileAtomically(path string, data []byte) error {
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	tempPath := tempFile.Name()
+
+	_, err = tempFile.Write(data)
+	if err == nil {
+		err = tempFile.Sync() // Otherwise a crash could leave an empty file behind, which would look like a cached copy
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(tempPath, 0644)
+	}
+
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+
+	if err != nil {
+		os.Remove(tempPath)
+	}
+
+	return err
+
+}
@@ -0,0 +1,310 @@
+package httpcache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testServer serves each path's body, counting the requests made and answering revalidations with 304 Not Modified
+// when the client's copy is still current.
+type testServer struct {
+	*httptest.Server
+	Bodies       map[string]string
+	ETag         string
+	LastModified string
+	Requests     int
+	Downloads    int // Requests answered with the whole body
+}
+
+func newTestServer() *testServer {
+
+	server := &testServer{Bodies: map[string]string{}}
+
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		server.Requests++
+
+		body, exists := server.Bodies[r.URL.Path]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+
+		if server.ETag != "" {
+			w.Header().Set("ETag", server.ETag)
+			if r.Header.Get("If-None-Match") == server.ETag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		if server.LastModified != "" {
+			w.Header().Set("Last-Modified", server.LastModified)
+			if r.Header.Get("If-Modified-Since") == server.LastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		server.Downloads++
+		fmt.Fprint(w, body)
+
+	}))
+
+	return server
+
+}
+
+func newTestCache(t *testing.T, maxSize int64) (*Cache, func()) {
+
+	directory, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(directory, maxSize), func() { os.RemoveAll(directory) }
+
+}
+
+func fetchBody(t *testing.T, cache *Cache, url string) string {
+
+	path, err := cache.Fetch(url)
+	if err != nil {
+		t.Fatalf("Fetch(%s) returned an error: %s", url, err)
+	}
+
+	defer cache.Release(url)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read the cached file: %s", err)
+	}
+
+	return string(data)
+
+}
+
+func TestFetch(t *testing.T) {
+
+	server := newTestServer()
+	defer server.Close()
+
+	server.Bodies["/notes.txt"] = "Buy more coffee"
+
+	cache, cleanup := newTestCache(t, 0)
+	defer cleanup()
+
+	if body := fetchBody(t, cache, server.URL+"/notes.txt"); body != "Buy more coffee" {
+		t.Errorf("got %q, want %q", body, "Buy more coffee")
+	}
+
+	if _, err := cache.Fetch(server.URL + "/missing.txt"); err == nil {
+		t.Errorf("fetching a file that isn't on the server didn't return an error")
+	}
+
+}
+
+func TestFetchRevalidatesWithETag(t *testing.T) {
+
+	server := newTestServer()
+	defer server.Close()
+
+	server.Bodies["/notes.txt"] = "Buy more coffee"
+	server.ETag = `"v1"`
+
+	cache, cleanup := newTestCache(t, 0)
+	defer cleanup()
+
+	url := server.URL + "/notes.txt"
+
+	fetchBody(t, cache, url)
+
+	if body := fetchBody(t, cache, url); body != "Buy more coffee" || server.Downloads != 1 || server.Requests != 2 {
+		t.Errorf("got %q after %d downloads in %d requests, want the cached copy revalidated without downloading it again", body, server.Downloads, server.Requests)
+	}
+
+	// A changed file is downloaded again.
+	server.Bodies["/notes.txt"] = "Buy more tea"
+	server.ETag = `"v2"`
+
+	if body := fetchBody(t, cache, url); body != "Buy more tea" || server.Downloads != 2 {
+		t.Errorf("got %q after %d downloads, want the changed file", body, server.Downloads)
+	}
+
+}
+
+func TestFetchRevalidatesWithLastModified(t *testing.T) {
+
+	server := newTestServer()
+	defer server.Close()
+
+	server.Bodies["/notes.txt"] = "Buy more coffee"
+	server.LastModified = time.Date(2020, 10, 12, 17, 33, 21, 0, time.UTC).Format(http.TimeFormat)
+
+	cache, cleanup := newTestCache(t, 0)
+	defer cleanup()
+
+	url := server.URL + "/notes.txt"
+
+	fetchBody(t, cache, url)
+
+	if body := fetchBody(t, cache, url); body != "Buy more coffee" || server.Downloads != 1 || server.Requests != 2 {
+		t.Errorf("got %q after %d downloads in %d requests, want the cached copy revalidated without downloading it again", body, server.Downloads, server.Requests)
+	}
+
+}
+
+func TestFetchJSON(t *testing.T) {
+
+	server := newTestServer()
+	defer server.Close()
+
+	server.Bodies["/data.json"] = `{"Name": "MasterPlan"}`
+	server.ETag = `"v1"`
+
+	cache, cleanup := newTestCache(t, 0)
+	defer cleanup()
+
+	url := server.URL + "/data.json"
+
+	path, err := cache.Fetch(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(path, ".json") || strings.HasSuffix(path, entrySuffix) {
+		t.Errorf("JSON file cached as [%s]", path)
+	}
+
+	// The downloaded file isn't mistaken for (or overwritten by) what the cache knows about it.
+	if body := fetchBody(t, cache, url); body != `{"Name": "MasterPlan"}` || server.Downloads != 1 {
+		t.Errorf("got %q after %d downloads, want the cached JSON file revalidated", body, server.Downloads)
+	}
+
+}
+
+func TestFetchOffline(t *testing.T) {
+
+	server := newTestServer()
+
+	server.Bodies["/notes.txt"] = "Buy more coffee"
+
+	cache, cleanup := newTestCache(t, 0)
+	defer cleanup()
+
+	url := server.URL + "/notes.txt"
+
+	fetchBody(t, cache, url)
+
+	server.Close()
+
+	if body := fetchBody(t, cache, url); body != "Buy more coffee" {
+		t.Errorf("got %q while offline, want the cached copy", body)
+	}
+
+	if _, err := cache.Fetch(server.URL + "/other.txt"); err == nil {
+		t.Errorf("fetching an uncached file while offline didn't return an error")
+	}
+
+}
+
+func TestEvict(t *testing.T) {
+
+	server := newTestServer()
+	defer server.Close()
+
+	for _, name := range []string{"a", "b", "c"} {
+		server.Bodies["/"+name+".txt"] = strings.Repeat(name, 10)
+	}
+
+	// Room for two of the files, but not all three.
+	cache, cleanup := newTestCache(t, 25)
+	defer cleanup()
+
+	paths := map[string]string{}
+
+	for _, name := range []string{"a", "b", "c"} {
+
+		path, err := cache.Fetch(server.URL + "/" + name + ".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cache.Release(server.URL + "/" + name + ".txt")
+
+		paths[name] = path
+
+		// Using "a" again makes "b" the least recently used.
+		if name == "b" {
+			time.Sleep(10 * time.Millisecond)
+			fetchBody(t, cache, server.URL+"/a.txt")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+	}
+
+	for name, evicted := range map[string]bool{"a": false, "b": true, "c": false} {
+
+		_, err := os.Stat(paths[name])
+
+		if evicted && err == nil {
+			t.Errorf("%s wasn't evicted", name)
+		} else if !evicted && err != nil {
+			t.Errorf("%s was evicted", name)
+		}
+
+	}
+
+	// The evicted file's entry goes along with it.
+	entries, _ := filepath.Glob(filepath.Join(cache.Directory, "*"+entrySuffix))
+	if len(entries) != 2 {
+		t.Errorf("got %d entries left, want 2", len(entries))
+	}
+
+}
+
+func TestEvictSkipsFilesInUse(t *testing.T) {
+
+	server := newTestServer()
+	defer server.Close()
+
+	for _, name := range []string{"a", "b"} {
+		server.Bodies["/"+name+".txt"] = strings.Repeat(name, 10)
+	}
+
+	// Only room for one of the files.
+	cache, cleanup := newTestCache(t, 15)
+	defer cleanup()
+
+	urlA, urlB := server.URL+"/a.txt", server.URL+"/b.txt"
+
+	pathA, err := cache.Fetch(urlA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	// "a" hasn't been released, so it's still being read and has to stay, even though it's the least recently used.
+	fetchBody(t, cache, urlB)
+
+	if _, err := os.Stat(pathA); err != nil {
+		t.Errorf("a was evicted while it was in use")
+	}
+
+	cache.Release(urlA)
+	cache.Evict()
+
+	if _, err := os.Stat(pathA); err == nil {
+		t.Errorf("a wasn't evicted once it was released")
+	}
+
+}
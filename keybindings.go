@@ -129,12 +129,15 @@ const (
 	KBZoomLevel1000           = "Zoom Level 1000%"
 	KBZoomIn                  = "Zoom In"
 	KBZoomOut                 = "Zoom Out"
+	KBZoomToFitAll            = "Zoom to Fit All Tasks"
+	KBZoomToFitSelection      = "Zoom to Fit Selected Tasks"
 	KBFasterPan               = "Faster Pan"
 	KBPanUp                   = "Pan Up"
 	KBPanDown                 = "Pan Down"
 	KBPanRight                = "Pan Right"
 	KBPanLeft                 = "Pan Left"
 	KBCenterView              = "Center View to Origin"
+	KBToggleMinimap           = "Toggle Minimap"
 	KBBoard1                  = "Switch to Board 1"
 	KBBoard2                  = "Switch to Board 2"
 	KBBoard3                  = "Switch to Board 3"
@@ -145,6 +148,11 @@ const (
 	KBBoard8                  = "Switch to Board 8"
 	KBBoard9                  = "Switch to Board 9"
 	KBBoard10                 = "Switch to Board 10"
+	KBNextBoard               = "Switch to Next Board"
+	KBPreviousBoard           = "Switch to Previous Board"
+	KBBoardHistoryBack        = "Go Back to Last Board"
+	KBBoardHistoryForward     = "Go Forward to Next Board"
+	KBQuickSwitchBoard        = "Quick Switch Board"
 	KBSelectAllTasks          = "Select All Tasks"
 	KBCopyTasks               = "Copy Tasks"
 	KBCutTasks                = "Cut Tasks / Text"
@@ -154,6 +162,7 @@ const (
 	KBStopAllSounds           = "Stop All Playing Sounds"
 	KBToggleTasks             = "Toggle Tasks"
 	KBDeleteTasks             = "Delete Tasks"
+	KBConnectTasks            = "Connect / Disconnect Tasks"
 	KBFocusOnTasks            = "Focus View on Tasks"
 	KBEditTasks               = "Edit Tasks"
 	KBDeselectTasks           = "Deselect All Tasks"
@@ -338,6 +347,8 @@ func (kb *Keybindings) Default() {
 
 	kb.Define(KBZoomIn, rl.KeyEqual).triggerMode = TriggerModeRepeating
 	kb.Define(KBZoomOut, rl.KeyMinus).triggerMode = TriggerModeRepeating
+	kb.Define(KBZoomToFitAll, rl.KeyHome)
+	kb.Define(KBZoomToFitSelection, rl.KeyF, rl.KeyLeftShift)
 
 	kb.Define(KBFasterPan, rl.KeyLeftShift).triggerMode = TriggerModeHold
 	kb.Define(KBPanUp, rl.KeyW).triggerMode = TriggerModeHold
@@ -346,6 +357,7 @@ func (kb *Keybindings) Default() {
 	kb.Define(KBPanRight, rl.KeyD).triggerMode = TriggerModeHold
 
 	kb.Define(KBCenterView, rl.KeyBackspace)
+	kb.Define(KBToggleMinimap, rl.KeyM)
 
 	kb.Define(KBBoard1, rl.KeyOne, rl.KeyLeftShift)
 	kb.Define(KBBoard2, rl.KeyTwo, rl.KeyLeftShift)
@@ -357,6 +369,11 @@ func (kb *Keybindings) Default() {
 	kb.Define(KBBoard8, rl.KeyEight, rl.KeyLeftShift)
 	kb.Define(KBBoard9, rl.KeyNine, rl.KeyLeftShift)
 	kb.Define(KBBoard10, rl.KeyZero, rl.KeyLeftShift)
+	kb.Define(KBNextBoard, rl.KeyPageDown, rl.KeyLeftControl)
+	kb.Define(KBPreviousBoard, rl.KeyPageUp, rl.KeyLeftControl)
+	kb.Define(KBBoardHistoryBack, rl.KeyLeft, rl.KeyLeftAlt)
+	kb.Define(KBBoardHistoryForward, rl.KeyRight, rl.KeyLeftAlt)
+	kb.Define(KBQuickSwitchBoard, rl.KeyP, rl.KeyLeftControl)
 
 	kb.Define(KBSelectAllTasks, rl.KeyA, rl.KeyLeftControl)
 	kb.Define(KBCopyTasks, rl.KeyC, rl.KeyLeftControl)
@@ -367,6 +384,7 @@ func (kb *Keybindings) Default() {
 	kb.Define(KBStopAllSounds, rl.KeyC, rl.KeyLeftShift)
 	kb.Define(KBToggleTasks, rl.KeyC)
 	kb.Define(KBDeleteTasks, rl.KeyDelete)
+	kb.Define(KBConnectTasks, rl.KeyL)
 	kb.Define(KBFocusOnTasks, rl.KeyF)
 	kb.Define(KBEditTasks, rl.KeyEnter)
 	kb.Define(KBDeselectTasks, rl.KeyEscape)
@@ -20,6 +20,7 @@ import (
 	"os"
 
 	"github.com/adrg/xdg"
+	"github.com/solarlune/masterplan/model"
 	"github.com/tidwall/gjson"
 )
 
@@ -34,7 +35,7 @@ var lineSpacing = float32(1) // This is assuming font size is the height, which
 var font rl.Font
 var not_shit_font rl.Font
 var windowTitle = "notMasterPlan"
-var softwareVersion = 1
+var softwareVersion = model.FileVersion
 var deltaTime = float32(0)
 var quit = false
 
@@ -46,6 +47,16 @@ type ProgramSettings struct {
 	WindowPosition            rl.Rectangle
 	SaveWindowPosition        bool
 	Keybindings               *Keybindings
+	MaxUndoSteps              int // 0 or less means the undo history is unlimited
+	BackupInterval            int // In minutes; 0 or less turns off automatic backups
+	BackupKeepCount           int // 0 or less keeps every backup
+	AutoSave                  bool
+	AutoSaveIdleSeconds       int // How long a modified project has to go untouched before it's saved automatically
+	ShowMinimap               bool
+	PauseAnimationsUnfocused  bool // Animated GIFs hold still while the window's unfocused, to save CPU
+	SoundVolume               float32 // From 0 (silent) to 1 (full volume)
+	HTTPCacheMaxMB            int     // How big the cache of downloaded resources can get; 0 or less is unlimited
+	CopyResourcesToAssets     bool    // Dropped and downloaded files are copied into the project's assets folder
 }
 
 var programSettings = ProgramSettings{
@@ -53,6 +64,15 @@ var programSettings = ProgramSettings{
   WindowPosition:         rl.NewRectangle(-1, -1, 0, 0),
   SaveWindowPosition:     true,
   Keybindings:            NewKeybindings(),
+  MaxUndoSteps:           100,
+  BackupInterval:         10,
+  BackupKeepCount:        5,
+  AutoSave:               true,
+  AutoSaveIdleSeconds:    30,
+  ShowMinimap:            true,
+  PauseAnimationsUnfocused: true,
+  SoundVolume:            0.8,
+  HTTPCacheMaxMB:         256,
 }
 
 func (ps *ProgramSettings) CleanUpRecentPlanList() {
@@ -67,15 +87,21 @@ func (ps *ProgramSettings) CleanUpRecentPlanList() {
 	ps.RecentPlanList = newList
 }
 
+func (ps *ProgramSettings) Load() {
+	path, _ := xdg.ConfigFile(SETTINGS_PATH)
+	settingsJSON, err := ioutil.ReadFile(path)
+
+	if err == nil {
+		json.Unmarshal(settingsJSON, ps)
+	}
+}
+
 func (ps *ProgramSettings) Save() {
 
 	path, _ := xdg.ConfigFile(SETTINGS_PATH)
-	f, err := os.Create(path)
-	if err == nil {
-		defer f.Close()
-		bytes, _ := json.Marshal(ps)
-		f.Write([]byte(gjson.Parse(string(bytes)).Get("@pretty").String()))
-		f.Sync()
+	bytes, _ := json.Marshal(ps)
+	if err := WriteFileAtomically(path, []byte(gjson.Parse(string(bytes)).Get("@pretty").String())); err != nil {
+		log.Println("Could not save program settings: ", err.Error())
 	}
 }
 
@@ -126,16 +152,14 @@ func main() {
 		}
 	}()
 
-	rl.SetTraceLog(rl.LogError)
+	programSettings.Load()
 
-  {
-    path, _ := xdg.ConfigFile(SETTINGS_PATH)
-    settingsJSON, err := ioutil.ReadFile(path)
+	// "MasterPlan plan <command> ..." runs a command on a project file without opening a window.
+	if len(os.Args) > 1 && os.Args[1] == "plan" {
+		os.Exit(RunCommandLine(os.Args[2:]))
+	}
 
-    if err == nil {
-      json.Unmarshal(settingsJSON, programSettings)
-    }
-  }
+	rl.SetTraceLog(rl.LogError)
 
 	windowFlags := byte(rl.FlagWindowResizable)
 
@@ -153,9 +177,9 @@ func main() {
 
 	ReloadFonts()
 
-	currentProject = NewProject()
+	currentProject = NewProject(windowViewport{}, windowInput{})
 
-	rl.SetExitKey(0) /// We don't want Escape to close the program.
+	rl.SetExitKey(0) /// We don't want Escape to close the program (or skip the unsaved changes prompt).
 
 	fpsDisplayValue := float32(0)
 	fpsDisplayAccumulator := float32(0)
@@ -169,10 +193,16 @@ func main() {
   ImGui_ImplRaylib_Init()
   imrend, _ := NewOpenGL3(imgui.CurrentIO())
 
-	for !rl.WindowShouldClose() && !quit {
+	for !quit {
 
 		currentTime := time.Now()
 
+		// Closing the window goes through quitting, so unsaved changes aren't thrown away without asking. raylib
+		// clears the close request each frame, so it can be turned down by choosing to cancel.
+		if rl.WindowShouldClose() {
+			currentProject.ExecuteDestructiveAction(ActionQuit, "")
+		}
+
 		handleMouseInputs()
 
 		if rl.IsKeyPressed(rl.KeyF1) {
@@ -199,6 +229,11 @@ func main() {
 
     rl.EndMode2D()
 
+    // Exports are rendered offscreen, which can't happen in the middle of drawing the Project.
+    currentProject.Exporter.HandlePending()
+
+    currentProject.Minimap.Draw()
+
     color := getThemeColor(GUI_FONT_COLOR)
     color.A = 128
 
@@ -264,7 +299,7 @@ func main() {
     }
 
     {
-      imgui.ShowDemoWindow(nil)
+      currentProject.DrawGUI()
 
       imgui.Render()
 
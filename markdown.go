@@ -0,0 +1,390 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Notes support a small subset of Markdown: headings, bold and italic text, bullet and numbered lists, inline
+// code, and links.
+
+type markdownStyle int
+
+const (
+	markdownBold markdownStyle = 1 << iota
+	markdownItalic
+	markdownCode
+	markdownLink
+	markdownBullet // Not text; the dot in front of a bullet list item
+)
+
+// markdownSpan is a run of text in a single style.
+type markdownSpan struct {
+	Text  string
+	Style markdownStyle
+	URL   string
+}
+
+// markdownBlock is a single line of Markdown source: a heading, a list item, or a paragraph.
+type markdownBlock struct {
+	Spans   []markdownSpan
+	Heading int
+	Bullet  bool
+	Number  string
+	Indent  int
+}
+
+// How much larger headings are than the note's text, from level 1 onwards.
+var markdownHeadingScales = []float32{1.6, 1.4, 1.2}
+
+var (
+	markdownHeadingRegex  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	markdownBulletRegex   = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	markdownNumberedRegex = regexp.MustCompile(`^(\d+)[.)]\s+(.*)$`)
+	markdownLinkRegex     = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// ParseMarkdown splits the text into blocks, one for each line.
+func ParseMarkdown(text string) []markdownBlock {
+
+	blocks := []markdownBlock{}
+
+	for _, line := range strings.Split(text, "\n") {
+
+		line = strings.TrimRight(line, "\r")
+
+		block := markdownBlock{}
+
+		spaces := 0
+		for _, c := range line {
+			if c == ' ' {
+				spaces++
+			} else if c == '\t' {
+				spaces += 4
+			} else {
+				break
+			}
+		}
+
+		content := strings.TrimLeft(line, " \t")
+
+		if match := markdownHeadingRegex.FindStringSubmatch(content); match != nil && spaces == 0 {
+			block.Heading = len(match[1])
+			content = match[2]
+		} else if match := markdownBulletRegex.FindStringSubmatch(content); match != nil {
+			block.Bullet = true
+			block.Indent = spaces / 2
+			content = match[1]
+		} else if match := markdownNumberedRegex.FindStringSubmatch(content); match != nil {
+			block.Number = match[1] + "."
+			block.Indent = spaces / 2
+			content = match[2]
+		} else {
+			content = line // Plain paragraphs keep their leading whitespace
+		}
+
+		block.Spans = parseMarkdownInline(content)
+
+		if block.Heading > 0 {
+			for i := range block.Spans {
+				block.Spans[i].Style |= markdownBold
+			}
+		}
+
+		blocks = append(blocks, block)
+
+	}
+
+	return blocks
+
+}
+
+// parseMarkdownInline splits a line into spans of bold, italic, code, and link text.
+func parseMarkdownInline(text string) []markdownSpan {
+
+	spans := []markdownSpan{}
+	style := markdownStyle(0)
+	current := strings.Builder{}
+
+	flush := func() {
+		if current.Len() > 0 {
+			spans = append(spans, markdownSpan{Text: current.String(), Style: style})
+			current.Reset()
+		}
+	}
+
+	// toggle turns the style on or off at the marker, but only turns it on if there's a closing marker later
+	// in the line; otherwise the marker's just text.
+	toggle := func(s markdownStyle, marker string, rest string) bool {
+		if style&s == 0 && !strings.Contains(rest[len(marker):], marker) {
+			return false
+		}
+		flush()
+		style ^= s
+		return true
+	}
+
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); i++ {
+
+		c := runes[i]
+		rest := string(runes[i:])
+
+		switch {
+
+		case c == '\\' && i+1 < len(runes) && strings.ContainsRune("\\`*_[]()#", runes[i+1]):
+			i++
+			current.WriteRune(runes[i])
+			continue
+
+		case c == '`':
+			if end := strings.IndexRune(rest[1:], '`'); end >= 0 {
+				flush()
+				spans = append(spans, markdownSpan{Text: rest[1 : end+1], Style: style | markdownCode})
+				i += len([]rune(rest[1:end+1])) + 1
+				continue
+			}
+
+		case c == '[':
+			if match := markdownLinkRegex.FindStringSubmatch(rest); match != nil {
+				flush()
+				spans = append(spans, markdownSpan{Text: match[1], Style: style | markdownLink, URL: match[2]})
+				i += len([]rune(match[0])) - 1
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if toggle(markdownBold, rest[:2], rest) {
+				i++
+				continue
+			}
+
+		case c == '*' || c == '_':
+			// Underscores inside of words (like snake_case) aren't emphasis.
+			inWord := c == '_' && i > 0 && i+1 < len(runes) && isMarkdownWordRune(runes[i-1]) && isMarkdownWordRune(runes[i+1])
+			if !inWord && toggle(markdownItalic, string(c), rest) {
+				continue
+			}
+
+		}
+
+		current.WriteRune(c)
+
+	}
+
+	flush()
+
+	return spans
+
+}
+
+func isMarkdownWordRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// markdownPiece is a span of text laid out at a position relative to the top-left of the note's text.
+type markdownPiece struct {
+	Text     string
+	Style    markdownStyle
+	URL      string
+	Position rl.Vector2
+	Size     rl.Vector2
+	FontSize float32
+}
+
+// MarkdownLayout is Markdown text that has been wrapped and positioned, ready to draw.
+type MarkdownLayout struct {
+	Pieces []markdownPiece
+	Size   rl.Vector2
+
+	text     string
+	width    float32
+	fontSize float32
+}
+
+func measureMarkdownText(text string, fontSize float32) rl.Vector2 {
+	return rl.MeasureTextEx(not_shit_font, text, fontSize, spacing)
+}
+
+// LayoutMarkdown lays out the Markdown text at the font size given, wrapping lines that are wider than maxWidth.
+// A maxWidth of 0 or less means lines don't wrap.
+func LayoutMarkdown(text string, fontSize, maxWidth float32) *MarkdownLayout {
+
+	layout := &MarkdownLayout{text: text, width: maxWidth, fontSize: fontSize}
+
+	y := float32(0)
+
+	for _, block := range ParseMarkdown(text) {
+
+		size := fontSize
+		if block.Heading > 0 && block.Heading <= len(markdownHeadingScales) {
+			size *= markdownHeadingScales[block.Heading-1]
+		}
+
+		lineHeight := size * lineSpacing
+		indent := float32(block.Indent) * fontSize * 2
+
+		// Where wrapped lines start; list items hang their text past the bullet or number.
+		lineStart := indent
+		x := indent
+
+		if block.Bullet || block.Number != "" {
+
+			prefix := markdownPiece{Position: rl.Vector2{indent, y}, FontSize: size}
+
+			if block.Bullet {
+				prefix.Style = markdownBullet
+				prefix.Size = rl.Vector2{size / 2, size}
+			} else {
+				prefix.Text = block.Number
+				prefix.Size = measureMarkdownText(block.Number, size)
+			}
+
+			layout.Pieces = append(layout.Pieces, prefix)
+
+			lineStart += prefix.Size.X + size/2
+			x = lineStart
+
+		}
+
+		place := func(token string, span markdownSpan) {
+
+			tokenSize := measureMarkdownText(token, size)
+
+			if maxWidth > 0 && x > lineStart && x+tokenSize.X > maxWidth {
+				x = lineStart
+				y += lineHeight
+				if strings.TrimSpace(token) == "" {
+					return // Wrapped lines don't start with a space
+				}
+			}
+
+			// Pieces of the same style next to each other are merged so there are fewer to draw.
+			if last := len(layout.Pieces) - 1; last >= 0 {
+				prev := &layout.Pieces[last]
+				if prev.Style == span.Style && prev.URL == span.URL && prev.FontSize == size && prev.Position.Y == y && prev.Position.X+prev.Size.X+spacing >= x {
+					prev.Text += token
+					prev.Size = measureMarkdownText(prev.Text, size)
+					x = prev.Position.X + prev.Size.X + spacing
+					return
+				}
+			}
+
+			layout.Pieces = append(layout.Pieces, markdownPiece{
+				Text:     token,
+				Style:    span.Style,
+				URL:      span.URL,
+				Position: rl.Vector2{x, y},
+				Size:     tokenSize,
+				FontSize: size,
+			})
+
+			x += tokenSize.X + spacing
+
+		}
+
+		for _, span := range block.Spans {
+
+			for _, token := range splitMarkdownWords(span.Text) {
+
+				// Words too long to fit on a line at all get broken up wherever they run out of room.
+				if maxWidth > 0 && measureMarkdownText(token, size).X > maxWidth-lineStart {
+					for _, c := range token {
+						place(string(c), span)
+					}
+					continue
+				}
+
+				place(token, span)
+
+			}
+
+		}
+
+		y += lineHeight
+
+	}
+
+	for _, piece := range layout.Pieces {
+		if right := piece.Position.X + piece.Size.X; right > layout.Size.X {
+			layout.Size.X = right
+		}
+	}
+
+	layout.Size.Y = y
+
+	return layout
+
+}
+
+// splitMarkdownWords splits the text into words and the runs of spaces between them, so the text can wrap
+// between words.
+func splitMarkdownWords(text string) []string {
+
+	words := []string{}
+	start := 0
+	inSpace := false
+
+	for i, c := range text {
+		space := c == ' ' || c == '\t'
+		if i > start && space != inSpace {
+			words = append(words, text[start:i])
+			start = i
+		}
+		inSpace = space
+	}
+
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+
+	return words
+
+}
+
+// Draw draws the laid out text with its top-left corner at the position given.
+func (layout *MarkdownLayout) Draw(position rl.Vector2, color rl.Color) {
+
+	for _, piece := range layout.Pieces {
+
+		pos := rl.Vector2Add(position, piece.Position)
+		pos.X = float32(int32(pos.X))
+		pos.Y = float32(int32(pos.Y))
+
+		pieceColor := color
+
+		switch {
+
+		case piece.Style&markdownBullet > 0:
+			rl.DrawCircleV(rl.Vector2{pos.X + piece.Size.X/2, pos.Y + piece.Size.Y/2}, piece.FontSize/8, color)
+			continue
+
+		case piece.Style&markdownCode > 0:
+			rl.DrawRectangleRec(rl.Rectangle{pos.X - 2, pos.Y, piece.Size.X + 4, piece.Size.Y}, rl.Fade(color, 0.15))
+
+		case piece.Style&markdownLink > 0:
+			pieceColor = rl.SkyBlue
+			thickness := piece.FontSize / 24
+			rl.DrawLineEx(rl.Vector2{pos.X, pos.Y + piece.Size.Y - thickness}, rl.Vector2{pos.X + piece.Size.X, pos.Y + piece.Size.Y - thickness}, thickness, pieceColor)
+
+		}
+
+		// There's only the one font, so italics are set apart by being fainter instead of slanted.
+		if piece.Style&markdownItalic > 0 {
+			pieceColor = rl.Fade(pieceColor, 0.7)
+		}
+
+		rl.DrawTextEx(not_shit_font, piece.Text, pos, piece.FontSize, spacing, pieceColor)
+
+		// ...and bold is drawn twice, slightly offset.
+		if piece.Style&markdownBold > 0 {
+			rl.DrawTextEx(not_shit_font, piece.Text, rl.Vector2{pos.X + piece.FontSize/32, pos.Y}, piece.FontSize, spacing, pieceColor)
+		}
+
+	}
+
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/inkyblackness/imgui-go/v3"
+)
+
+// DrawGUI draws the Project's imgui windows; it has to be called between imgui.NewFrame() and imgui.Render().
+func (project *Project) DrawGUI() {
+
+	project.DrawMainMenu()
+
+	for _, task := range project.GetAllTasks() {
+		task.PostDraw()
+	}
+
+	project.DrawUnsavedChangesPrompt()
+
+	if project.BoardPanelOpen {
+		project.DrawBoardPanel()
+	} else {
+		project.BoardPanel = rl.Rectangle{}
+	}
+
+	project.drawBoardPicker()
+
+	project.Search.Draw()
+
+	project.Exporter.Draw()
+
+	if project.BackupsOpen {
+		project.DrawBackupsWindow()
+	}
+
+	if project.UnusedAssetsOpen {
+		project.DrawAssetsWindow()
+	}
+
+}
+
+func (project *Project) DrawMainMenu() {
+
+	shortcut := func(bindingName string) string {
+		return programSettings.Keybindings.Shortcuts[bindingName].String()
+	}
+
+	if imgui.BeginMainMenuBar() {
+
+		if imgui.BeginMenu("File") {
+
+			if imgui.MenuItem("New") {
+				project.ExecuteDestructiveAction(ActionNewProject, "")
+			}
+
+			if imgui.MenuItemV("Open...", shortcut(KBLoad), false, true) {
+				project.ExecuteDestructiveAction(ActionLoadProject, "")
+			}
+
+			if imgui.MenuItemV("Save", shortcut(KBSave), false, true) {
+				if project.FilePath == "" {
+					project.SaveAs()
+				} else {
+					project.Save(false)
+				}
+			}
+
+			if imgui.MenuItemV("Save As...", shortcut(KBSaveAs), false, true) {
+				project.SaveAs()
+			}
+
+			imgui.Separator()
+
+			if imgui.MenuItem("Open Bundle...") {
+				project.ExecuteDestructiveAction(ActionOpenBundle, "")
+			}
+
+			if imgui.MenuItem("Export Bundle...") {
+				project.ExportBundleAs()
+			}
+
+			if imgui.MenuItemV("Export PNG...", "", project.Exporter.Open, true) {
+				project.Exporter.Open = !project.Exporter.Open
+			}
+
+			imgui.Separator()
+
+			if imgui.MenuItemV("Autosave", "", programSettings.AutoSave, true) {
+				programSettings.AutoSave = !programSettings.AutoSave
+				programSettings.Save()
+			}
+
+			imgui.Separator()
+
+			if imgui.MenuItemV("Backups...", "", project.BackupsOpen, project.FilePath != "") {
+				project.BackupsOpen = !project.BackupsOpen
+			}
+
+			if imgui.BeginMenu("Assets") {
+
+				if imgui.MenuItemV("Copy Dropped & Downloaded Files Into Assets", "", programSettings.CopyResourcesToAssets, true) {
+					programSettings.CopyResourcesToAssets = !programSettings.CopyResourcesToAssets
+					programSettings.Save()
+				}
+
+				if imgui.MenuItemV("Collect All External Resources", "", false, project.FilePath != "") {
+					project.CollectResources()
+				}
+
+				if imgui.MenuItemV("Find Unused Assets...", "", project.UnusedAssetsOpen, project.FilePath != "") {
+					project.UnusedAssetsOpen = !project.UnusedAssetsOpen
+					project.UnusedAssetList = nil
+				}
+
+				imgui.EndMenu()
+			}
+
+			imgui.Separator()
+
+			if imgui.MenuItem("Quit") {
+				project.ExecuteDestructiveAction(ActionQuit, "")
+			}
+
+			imgui.EndMenu()
+		}
+
+		if imgui.BeginMenu("View") {
+
+			if imgui.MenuItemV("Boards", "", project.BoardPanelOpen, true) {
+				project.BoardPanelOpen = !project.BoardPanelOpen
+			}
+
+			if imgui.MenuItemV("Minimap", shortcut(KBToggleMinimap), programSettings.ShowMinimap, true) {
+				programSettings.ShowMinimap = !programSettings.ShowMinimap
+				programSettings.Save()
+			}
+
+			if imgui.MenuItemV("Pause Animations When Unfocused", "", programSettings.PauseAnimationsUnfocused, true) {
+				programSettings.PauseAnimationsUnfocused = !programSettings.PauseAnimationsUnfocused
+				programSettings.Save()
+			}
+
+			imgui.Separator()
+
+			if imgui.MenuItemV("Stop All Sounds", shortcut(KBStopAllSounds), false, true) {
+				project.StopAllSounds()
+			}
+
+			if imgui.SliderFloatV("Volume", &programSettings.SoundVolume, 0, 1, "%.2f", 1) {
+				project.UpdateSoundVolume()
+				programSettings.Save()
+			}
+
+			imgui.Separator()
+
+			if imgui.MenuItemV("Zoom In", shortcut(KBZoomIn), false, true) {
+				project.ZoomCentered(project.Zoom * 1.25)
+			}
+
+			if imgui.MenuItemV("Zoom Out", shortcut(KBZoomOut), false, true) {
+				project.ZoomCentered(project.Zoom / 1.25)
+			}
+
+			if imgui.MenuItemV("Actual Size", shortcut(KBZoomLevel100), false, true) {
+				project.ZoomCentered(1)
+			}
+
+			if imgui.MenuItemV("Zoom to Fit All Tasks", shortcut(KBZoomToFitAll), false, len(project.CurrentBoard().Tasks) > 0) {
+				project.ZoomToFit(project.CurrentBoard().Tasks)
+			}
+
+			if imgui.MenuItemV("Zoom to Fit Selection", shortcut(KBZoomToFitSelection), false, len(project.CurrentBoard().SelectedTasks(true)) > 0) {
+				project.ZoomToFit(project.CurrentBoard().SelectedTasks(false))
+			}
+
+			imgui.Separator()
+
+			if imgui.MenuItemV("Next Board", shortcut(KBNextBoard), false, len(project.Boards) > 1) {
+				project.SwitchBoardRelative(1)
+			}
+
+			if imgui.MenuItemV("Previous Board", shortcut(KBPreviousBoard), false, len(project.Boards) > 1) {
+				project.SwitchBoardRelative(-1)
+			}
+
+			if imgui.MenuItemV("Back", shortcut(KBBoardHistoryBack), false, project.BoardHistoryIndex > 0) {
+				project.NavigateBoardHistory(-1)
+			}
+
+			if imgui.MenuItemV("Forward", shortcut(KBBoardHistoryForward), false, project.BoardHistoryIndex < len(project.BoardHistory)-1) {
+				project.NavigateBoardHistory(1)
+			}
+
+			if imgui.MenuItemV("Switch Board...", shortcut(KBQuickSwitchBoard), false, true) {
+				project.OpenBoardPicker()
+			}
+
+			imgui.Separator()
+
+			if imgui.MenuItemV("Find...", shortcut(KBFindNextTask), project.Search.Open, true) {
+				project.Search.Show()
+			}
+
+			imgui.EndMenu()
+		}
+
+		imgui.EndMainMenuBar()
+	}
+
+}
+
+// DrawUnsavedChangesPrompt asks the user whether to save before the pending destructive action throws away their changes.
+func (project *Project) DrawUnsavedChangesPrompt() {
+
+	if project.OpenUnsavedChangesPrompt {
+		imgui.OpenPopup("Unsaved Changes")
+		project.OpenUnsavedChangesPrompt = false
+	}
+
+	if imgui.BeginPopupModalV("Unsaved Changes", nil, imgui.WindowFlagsAlwaysAutoResize) {
+
+		imgui.Text("This project has unsaved changes. Save them first?")
+
+		action, argument := project.PendingAction, project.PendingActionArgument
+		decided := false
+
+		if imgui.Button("Save") {
+
+			if project.FilePath == "" {
+				project.SaveAs()
+			} else {
+				project.Save(false)
+			}
+
+			decided = true
+
+			// Canceling the save dialog or failing to save cancels the action, too
+			if project.Modified {
+				action = ""
+			}
+
+		}
+
+		imgui.SameLine()
+
+		if imgui.Button("Don't Save") {
+			decided = true
+		}
+
+		imgui.SameLine()
+
+		if imgui.Button("Cancel") {
+			decided = true
+			action = ""
+		}
+
+		if decided {
+
+			imgui.CloseCurrentPopup()
+
+			project.PendingAction = ""
+			project.PendingActionArgument = ""
+
+			if action != "" {
+				project.executeAction(action, argument)
+			}
+
+		}
+
+		imgui.EndPopup()
+	}
+
+}
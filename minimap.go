@@ -0,0 +1,245 @@
+package main
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	minimapWidth  = 240
+	minimapHeight = 160
+	minimapMargin = 16
+)
+
+// Minimap is the overlay in the corner of the window showing where the current Board's Tasks are and what part of
+// the Board is in view. The Tasks are drawn into a texture that's only redrawn when they change, so the overlay
+// stays cheap with thousands of Tasks on a Board.
+type Minimap struct {
+	Project  *Project
+	Dragging bool
+
+	texture       rl.RenderTexture2D
+	textureBounds rl.Rectangle // The area of the world the texture shows
+	signature     float64
+	board         *Board
+
+	// The area of the world the minimap shows; it's kept as it is while dragging so the map doesn't shift under the mouse.
+	bounds rl.Rectangle
+}
+
+func NewMinimap(project *Project) *Minimap {
+	return &Minimap{Project: project}
+}
+
+// Rect returns where the minimap is on screen.
+func (minimap *Minimap) Rect() rl.Rectangle {
+	return rl.Rectangle{
+		float32(rl.GetScreenWidth()) - minimapWidth - minimapMargin,
+		float32(rl.GetScreenHeight()) - minimapHeight - minimapMargin,
+		minimapWidth,
+		minimapHeight,
+	}
+}
+
+// Visible returns if the minimap's being shown.
+func (minimap *Minimap) Visible() bool {
+	return programSettings.ShowMinimap && len(minimap.Project.CurrentBoard().Tasks) > 0
+}
+
+func (minimap *Minimap) taskBounds() rl.Rectangle {
+
+	tasks := minimap.Project.CurrentBoard().Tasks
+
+	bounds := tasks[0].Rect
+	for _, task := range tasks[1:] {
+		bounds = rectUnion(bounds, task.Rect)
+	}
+
+	return bounds
+
+}
+
+// fitToMinimap returns the area of the world to show so that the rectangle fits in the minimap, centered and keeping its
+// aspect ratio, along with the scale from world to minimap.
+func fitToMinimap(rect rl.Rectangle, width, height float32) (rl.Rectangle, float32) {
+
+	scale := width / rect.Width
+	if s := height / rect.Height; s < scale {
+		scale = s
+	}
+
+	w, h := width/scale, height/scale
+
+	return rl.Rectangle{rect.X + rect.Width/2 - w/2, rect.Y + rect.Height/2 - h/2, w, h}, scale
+
+}
+
+func (minimap *Minimap) worldToScreen(world rl.Vector2) rl.Vector2 {
+	rect := minimap.Rect()
+	scale := rect.Width / minimap.bounds.Width
+	return rl.Vector2{rect.X + (world.X-minimap.bounds.X)*scale, rect.Y + (world.Y-minimap.bounds.Y)*scale}
+}
+
+func (minimap *Minimap) screenToWorld(screen rl.Vector2) rl.Vector2 {
+	rect := minimap.Rect()
+	scale := rect.Width / minimap.bounds.Width
+	return rl.Vector2{minimap.bounds.X + (screen.X-rect.X)/scale, minimap.bounds.Y + (screen.Y-rect.Y)/scale}
+}
+
+// Update pans the camera to wherever in the minimap is clicked or dragged to.
+func (minimap *Minimap) Update() {
+
+	if !minimap.Visible() {
+		minimap.Dragging = false
+		return
+	}
+
+	if !minimap.Dragging {
+
+		// The part of the Board in view is always on the map, so its outline can't go off the edge.
+		padding := float32(minimap.Project.GridSize) * 4
+		bounds := rectUnion(minimap.taskBounds(), rlRect(minimap.Project.Viewport.VisibleRect()))
+		bounds = rl.Rectangle{bounds.X - padding, bounds.Y - padding, bounds.Width + padding*2, bounds.Height + padding*2}
+
+		minimap.bounds, _ = fitToMinimap(bounds, minimapWidth, minimapHeight)
+
+	}
+
+	if MousePressed(rl.MouseLeftButton) && minimap.Project.MousingOver() == "Minimap" {
+		minimap.Dragging = true
+		ConsumeMouseInput(rl.MouseLeftButton)
+	}
+
+	if minimap.Dragging {
+
+		if !MouseDown(rl.MouseLeftButton) && !MousePressed(rl.MouseLeftButton) {
+			minimap.Dragging = false
+			return
+		}
+
+		world := minimap.screenToWorld(GetMousePosition())
+		minimap.Project.CameraPan = rl.Vector2{-world.X, -world.Y}
+		minimap.Project.CameraOffset = minimap.Project.CameraPan
+
+	}
+
+}
+
+// signatureOf returns a number that changes whenever anything drawn in the minimap's texture changes.
+func (minimap *Minimap) signatureOf(board *Board) float64 {
+
+	signature := float64(len(board.Tasks))
+
+	for i, task := range board.Tasks {
+		s := float64(task.Rect.X)*31 + float64(task.Rect.Y)*17 + float64(task.Rect.Width)*7 + float64(task.Rect.Height)*3
+		if task.Selected {
+			s += 1
+		}
+		signature += s * float64(i+1)
+	}
+
+	return signature
+
+}
+
+func (minimap *Minimap) redraw() {
+
+	board := minimap.Project.CurrentBoard()
+
+	if minimap.texture.ID == 0 {
+		minimap.texture = rl.LoadRenderTexture(minimapWidth, minimapHeight)
+	}
+
+	bounds, scale := fitToMinimap(minimap.taskBounds(), minimapWidth, minimapHeight)
+	minimap.textureBounds = bounds
+
+	noteColor := getThemeColor(GUI_NOTE_COLOR)
+	imageColor := getThemeColor(GUI_INSIDE_HIGHLIGHTED)
+	selectedColor := getThemeColor(GUI_OUTLINE_HIGHLIGHTED)
+
+	rl.BeginTextureMode(minimap.texture)
+	rl.ClearBackground(rl.Color{})
+
+	for _, task := range board.Tasks {
+
+		rect := rl.Rectangle{
+			(task.Rect.X - bounds.X) * scale,
+			(task.Rect.Y - bounds.Y) * scale,
+			task.Rect.Width * scale,
+			task.Rect.Height * scale,
+		}
+
+		// Tiny Tasks should still show up as something
+		if rect.Width < 1 {
+			rect.Width = 1
+		}
+		if rect.Height < 1 {
+			rect.Height = 1
+		}
+
+		color := noteColor
+		if task.Is(TASK_TYPE_IMAGE) {
+			color = imageColor
+		}
+
+		if task.Selected {
+			color = selectedColor
+		}
+
+		rl.DrawRectangleRec(rect, color)
+
+	}
+
+	rl.EndTextureMode()
+
+}
+
+// Draw draws the minimap in screen space, so it has to be called outside of the camera's 2D mode.
+func (minimap *Minimap) Draw() {
+
+	if !minimap.Visible() {
+		return
+	}
+
+	board := minimap.Project.CurrentBoard()
+
+	if signature := minimap.signatureOf(board); minimap.board != board || signature != minimap.signature || minimap.texture.ID == 0 {
+		minimap.redraw()
+		minimap.board = board
+		minimap.signature = signature
+	}
+
+	rect := minimap.Rect()
+
+	background := getThemeColor(GUI_INSIDE)
+	background.A = 200
+	rl.DrawRectangleRec(rect, background)
+
+	topLeft := minimap.worldToScreen(rl.Vector2{minimap.textureBounds.X, minimap.textureBounds.Y})
+	bottomRight := minimap.worldToScreen(rl.Vector2{minimap.textureBounds.X + minimap.textureBounds.Width, minimap.textureBounds.Y + minimap.textureBounds.Height})
+
+	// Render textures are upside down, hence the negative height.
+	src := rl.Rectangle{0, 0, minimapWidth, -minimapHeight}
+	dst := rl.Rectangle{topLeft.X, topLeft.Y, bottomRight.X - topLeft.X, bottomRight.Y - topLeft.Y}
+
+	rl.BeginScissorMode(int32(rect.X), int32(rect.Y), int32(rect.Width), int32(rect.Height))
+
+	rl.DrawTexturePro(minimap.texture.Texture, src, dst, rl.Vector2{}, 0, rl.White)
+
+	view := rlRect(minimap.Project.Viewport.VisibleRect())
+	viewTopLeft := minimap.worldToScreen(rl.Vector2{view.X, view.Y})
+	viewBottomRight := minimap.worldToScreen(rl.Vector2{view.X + view.Width, view.Y + view.Height})
+	rl.DrawRectangleLinesEx(rl.Rectangle{viewTopLeft.X, viewTopLeft.Y, viewBottomRight.X - viewTopLeft.X, viewBottomRight.Y - viewTopLeft.Y}, 1, getThemeColor(GUI_FONT_COLOR))
+
+	rl.EndScissorMode()
+
+	rl.DrawRectangleLinesEx(rect, 1, getThemeColor(GUI_OUTLINE))
+
+}
+
+// Destroy frees the minimap's texture.
+func (minimap *Minimap) Destroy() {
+	if minimap.texture.ID > 0 {
+		rl.UnloadRenderTexture(minimap.texture)
+		minimap.texture = rl.RenderTexture2D{}
+	}
+}
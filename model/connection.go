@@ -0,0 +1,65 @@
+package model
+
+import (
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConnectionData is a Connection between two Tasks as it's stored, with the Tasks referred to by ID.
+type ConnectionData struct {
+	Start, End int
+	ArrowStart bool
+	ArrowEnd   bool
+	Routing    string
+	Label      string
+	Color      [4]uint8 // Red, green, blue and alpha; a Color with no alpha isn't stored
+}
+
+// Serialize returns the Connection data as a JSON object in a string.
+func (data ConnectionData) Serialize() string {
+
+	jsonData := "{}"
+
+	jsonData, _ = sjson.Set(jsonData, `Start`, data.Start)
+	jsonData, _ = sjson.Set(jsonData, `End`, data.End)
+	jsonData, _ = sjson.Set(jsonData, `ArrowStart`, data.ArrowStart)
+	jsonData, _ = sjson.Set(jsonData, `ArrowEnd`, data.ArrowEnd)
+	jsonData, _ = sjson.Set(jsonData, `Routing`, data.Routing)
+
+	if data.Label != "" {
+		jsonData, _ = sjson.Set(jsonData, `Label`, data.Label)
+	}
+
+	if data.Color[3] > 0 {
+		// Set as ints, as sjson would encode a []uint8 as a base64 string.
+		jsonData, _ = sjson.Set(jsonData, `Color`, []int{int(data.Color[0]), int(data.Color[1]), int(data.Color[2]), int(data.Color[3])})
+	}
+
+	return jsonData
+
+}
+
+// ParseConnectionData reads Connection data from a JSON object in a string, as written by
+// ConnectionData.Serialize().
+func ParseConnectionData(jsonData string) ConnectionData {
+
+	parsed := gjson.Parse(jsonData)
+
+	data := ConnectionData{
+		Start:      int(parsed.Get(`Start`).Int()),
+		End:        int(parsed.Get(`End`).Int()),
+		ArrowStart: parsed.Get(`ArrowStart`).Bool(),
+		ArrowEnd:   parsed.Get(`ArrowEnd`).Bool(),
+		Routing:    parsed.Get(`Routing`).String(),
+		Label:      parsed.Get(`Label`).String(),
+	}
+
+	if color := parsed.Get(`Color`).Array(); len(color) == 4 {
+		for i := range data.Color {
+			data.Color[i] = uint8(color[i].Int())
+		}
+	}
+
+	return data
+
+}
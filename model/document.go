@@ -0,0 +1,153 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Document is a project as it's stored in a .plan file.
+type Document struct {
+	Version     int
+	BoardIndex  int // The current Board
+	Pan         Vector
+	Zoom        float32
+	ColorTheme  string
+	GridSize    int32
+	Boards      []BoardData
+	Tasks       []TaskData
+	Connections []ConnectionData
+}
+
+// BoardData is a Board's name and the view the user had of it.
+type BoardData struct {
+	Name string
+	Pan  Vector
+	Zoom float32
+}
+
+// Serialize returns the Document as the JSON that goes in a .plan file. Local files are stored relative to the
+// project file given (see SerializeFilePath()).
+func (document *Document) Serialize(projectPath string) string {
+
+	// Sort the Tasks by their ID, then loop through them using that slice. This way,
+	// They store data according to their creation ID, not according to their position
+	// in the world.
+	tasksByID := append([]TaskData{}, document.Tasks...)
+
+	sort.SliceStable(tasksByID, func(i, j int) bool { return tasksByID[i].ID < tasksByID[j].ID })
+
+	taskData := `[]`
+	for _, task := range tasksByID {
+		taskData, _ = sjson.SetRaw(taskData, `-1`, task.Serialize(projectPath))
+	}
+
+	data := `{}`
+
+	// Not handling any of these errors because uuuuuuuuuh idkkkkkk should there ever really be errors
+	// with a blank JSON {} object????
+	data, _ = sjson.Set(data, `Version`, document.Version)
+	data, _ = sjson.Set(data, `BoardIndex`, document.BoardIndex)
+	data, _ = sjson.Set(data, `BoardCount`, len(document.Boards))
+	data, _ = sjson.Set(data, `Pan\.X`, document.Pan.X)
+	data, _ = sjson.Set(data, `Pan\.Y`, document.Pan.Y)
+	data, _ = sjson.Set(data, `Zoom`, document.Zoom)
+	data, _ = sjson.Set(data, `ColorTheme`, document.ColorTheme)
+	data, _ = sjson.Set(data, `GridSize`, document.GridSize)
+
+	boardNames := []string{}
+	for _, board := range document.Boards {
+		boardNames = append(boardNames, board.Name)
+	}
+	data, _ = sjson.Set(data, `BoardNames`, boardNames)
+
+	// The current Board's view is also saved as Pan and Zoom, which is what older versions read.
+	boardViews := `[]`
+	for _, board := range document.Boards {
+		view := `{}`
+		view, _ = sjson.Set(view, `Pan\.X`, board.Pan.X)
+		view, _ = sjson.Set(view, `Pan\.Y`, board.Pan.Y)
+		view, _ = sjson.Set(view, `Zoom`, board.Zoom)
+		boardViews, _ = sjson.SetRaw(boardViews, `-1`, view)
+	}
+	data, _ = sjson.SetRaw(data, `BoardViews`, boardViews)
+
+	data, _ = sjson.SetRaw(data, `Tasks`, taskData) // taskData is already properly encoded and formatted JSON
+
+	connectionData := `[]`
+	for _, connection := range document.Connections {
+		connectionData, _ = sjson.SetRaw(connectionData, `-1`, connection.Serialize())
+	}
+	data, _ = sjson.SetRaw(data, `Connections`, connectionData)
+
+	return gjson.Parse(data).Get("@pretty").String() // Pretty print it so it's visually nice in the .plan file.
+
+}
+
+// ParseDocument reads a Document from the contents of a .plan file, upgrading it from older versions first (see
+// MigrateProject()). Relative file paths are taken to be relative to the project file given. The Document always
+// has at least one Board; Tasks can still refer to Boards that don't exist in a mangled file, though.
+func ParseDocument(fileData string, projectPath string) (*Document, error) {
+
+	jsonData, err := MigrateProject(fileData)
+	if err != nil {
+		return nil, err
+	}
+
+	data := gjson.Parse(jsonData)
+
+	if !data.Get(`Tasks`).IsArray() {
+		return nil, fmt.Errorf("there are no Tasks")
+	}
+
+	getFloat := func(name string) float32 {
+		return float32(data.Get(name).Float())
+	}
+
+	document := &Document{
+		Version:    int(data.Get(`Version`).Int()),
+		BoardIndex: int(data.Get(`BoardIndex`).Int()),
+		Pan:        Vector{getFloat(`Pan\.X`), getFloat(`Pan\.Y`)},
+		Zoom:       getFloat(`Zoom`),
+		ColorTheme: data.Get(`ColorTheme`).String(),
+		GridSize:   int32(data.Get(`GridSize`).Int()),
+	}
+
+	boardCount := int(data.Get(`BoardCount`).Int())
+	if boardCount < 1 {
+		boardCount = 1
+	}
+
+	boardNames := data.Get(`BoardNames`).Array()
+	boardViews := data.Get(`BoardViews`).Array()
+
+	for i := 0; i < boardCount; i++ {
+
+		board := BoardData{Name: fmt.Sprintf("Board %d", i+1), Zoom: 1}
+
+		if i < len(boardNames) {
+			board.Name = boardNames[i].String()
+		}
+
+		if i < len(boardViews) && boardViews[i].Get(`Zoom`).Float() > 0 {
+			board.Pan = Vector{float32(boardViews[i].Get(`Pan\.X`).Float()), float32(boardViews[i].Get(`Pan\.Y`).Float())}
+			board.Zoom = float32(boardViews[i].Get(`Zoom`).Float())
+		}
+
+		document.Boards = append(document.Boards, board)
+
+	}
+
+	for _, taskData := range data.Get(`Tasks`).Array() {
+		document.Tasks = append(document.Tasks, ParseTaskData(taskData.Raw, projectPath))
+	}
+
+	for _, connectionData := range data.Get(`Connections`).Array() {
+		document.Connections = append(document.Connections, ParseConnectionData(connectionData.Raw))
+	}
+
+	return document, nil
+
+}
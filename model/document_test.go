@@ -0,0 +1,163 @@
+package model
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+func testDocument(projectPath string) *Document {
+
+	created := time.Date(2020, 10, 12, 17, 33, 21, 0, time.UTC)
+
+	return &Document{
+		Version:    FileVersion,
+		BoardIndex: 1,
+		Pan:        Vector{128, -64},
+		Zoom:       2,
+		ColorTheme: "Sunlight",
+		GridSize:   16,
+		Boards: []BoardData{
+			{Name: "Ideas", Pan: Vector{-32, 48}, Zoom: 0.5},
+			{Name: "Chores", Pan: Vector{128, -64}, Zoom: 2},
+		},
+		Tasks: []TaskData{
+			{ID: 2, BoardIndex: 1, Position: Vector{32, 48}, DisplaySize: Vector{64, 16}, Description: "Take out the trash", TaskType: TASK_TYPE_CHECKBOX, CreationTime: created, TextSize: 16, Checked: true},
+			{ID: 0, BoardIndex: 0, Position: Vector{-16, 0}, DisplaySize: Vector{200, 100}, FilePath: filepath.Join(filepath.Dir(projectPath), "assets", "cat.png"), Selected: true, TaskType: TASK_TYPE_IMAGE, CreationTime: created, AnimationPaused: true},
+			{ID: 1, BoardIndex: 0, Position: Vector{64, 64}, DisplaySize: Vector{96, 32}, Description: "Levels done", TaskType: TASK_TYPE_PROGRESSION, CreationTime: created, TextSize: 24, ProgressionCurrent: 3, ProgressionMax: 10},
+			{ID: 3, BoardIndex: 0, Position: Vector{0, 128}, DisplaySize: Vector{32, 32}, FilePath: "https://example.com/theme.ogg", TaskType: TASK_TYPE_SOUND, CreationTime: created},
+		},
+		Connections: []ConnectionData{
+			{Start: 0, End: 1, ArrowEnd: true, Routing: "curved", Label: "then", Color: [4]uint8{255, 0, 0, 255}},
+			{Start: 1, End: 3, ArrowStart: true, Routing: "straight"},
+		},
+	}
+
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+
+	projectPath := filepath.Join(string(filepath.Separator), "plans", "chores.plan")
+
+	document := testDocument(projectPath)
+
+	loaded, err := ParseDocument(document.Serialize(projectPath), projectPath)
+	if err != nil {
+		t.Fatalf("ParseDocument() returned an error: %s", err)
+	}
+
+	// The Tasks are saved in order of their IDs.
+	want := testDocument(projectPath)
+	want.Tasks = []TaskData{want.Tasks[1], want.Tasks[2], want.Tasks[0], want.Tasks[3]}
+
+	if !reflect.DeepEqual(loaded, want) {
+		t.Errorf("loaded document doesn't match the saved one:\n got: %+v\nwant: %+v", loaded, want)
+	}
+
+}
+
+func TestDocumentStoresLocalFilesRelatively(t *testing.T) {
+
+	projectPath := filepath.Join(string(filepath.Separator), "plans", "chores.plan")
+
+	data := testDocument(projectPath).Serialize(projectPath)
+
+	image := gjson.Get(data, `Tasks.#(ID==0).FilePath`)
+	if components := image.Array(); !image.IsArray() || len(components) != 2 || components[0].String() != "assets" || components[1].String() != "cat.png" {
+		t.Errorf("local file stored as %s, not relative to the project file", image.Raw)
+	}
+
+	sound := gjson.Get(data, `Tasks.#(ID==3).FilePath`)
+	if sound.String() != "https://example.com/theme.ogg" {
+		t.Errorf("URL stored as %s", sound.Raw)
+	}
+
+	// Moving the project moves where its local files are looked for along with it.
+	movedPath := filepath.Join(string(filepath.Separator), "moved", "chores.plan")
+
+	moved, err := ParseDocument(data, movedPath)
+	if err != nil {
+		t.Fatalf("ParseDocument() returned an error: %s", err)
+	}
+
+	if want := filepath.Join(string(filepath.Separator), "moved", "assets", "cat.png"); moved.Tasks[0].FilePath != want {
+		t.Errorf("moved project's image is at %s, want %s", moved.Tasks[0].FilePath, want)
+	}
+
+}
+
+func TestParseDocumentMigratesMasterPlan(t *testing.T) {
+
+	data := `{
+		"Version": "0.6.0",
+		"ZoomLevel": 3,
+		"GridSize": 16,
+		"Tasks": [
+			{"Position.X": 0, "Position.Y": 0, "Description": "Groceries", "TaskType.CurrentChoice": 0, "Checkbox.Checked": true},
+			{"Position.X": 16, "Position.Y": 0, "TaskType.CurrentChoice": 5, "TimerName.Text": "Tea"},
+			{"Position.X": 32, "Position.Y": 0, "Description": "Notes", "TaskType.CurrentChoice": 2},
+			{"Position.X": 48, "Position.Y": 0, "TaskType.CurrentChoice": 4, "FilePath": ["sounds", "ding.wav"]}
+		]
+	}`
+
+	projectPath := filepath.Join(string(filepath.Separator), "plans", "old.plan")
+
+	document, err := ParseDocument(data, projectPath)
+	if err != nil {
+		t.Fatalf("ParseDocument() returned an error: %s", err)
+	}
+
+	if document.Zoom != 2 {
+		t.Errorf("zoom level 3 became zoom %f, want 2", document.Zoom)
+	}
+
+	if len(document.Boards) != 1 || document.Boards[0].Name != "Board 1" {
+		t.Errorf("got Boards %+v, want the one default Board", document.Boards)
+	}
+
+	if len(document.Tasks) != 4 {
+		t.Fatalf("got %d Tasks, want 4", len(document.Tasks))
+	}
+
+	if task := document.Tasks[0]; task.TaskType != TASK_TYPE_CHECKBOX || !task.Checked || task.Description != "Groceries" {
+		t.Errorf("checkbox Task became %+v", task)
+	}
+
+	// Timers don't exist here, so they become notes named after the timer.
+	if task := document.Tasks[1]; task.TaskType != TASK_TYPE_NOTE || task.Description != "Tea" {
+		t.Errorf("timer Task became %+v", task)
+	}
+
+	if task := document.Tasks[2]; task.TaskType != TASK_TYPE_NOTE || task.Description != "Notes" {
+		t.Errorf("note Task became %+v", task)
+	}
+
+	// Sound Tasks keep playing the same file.
+	if task := document.Tasks[3]; task.TaskType != TASK_TYPE_SOUND || task.FilePath != filepath.Join(filepath.Dir(projectPath), "sounds", "ding.wav") {
+		t.Errorf("sound Task became %+v", task)
+	}
+
+	// Tasks from original MasterPlan don't have IDs.
+	for _, task := range document.Tasks {
+		if task.ID != -1 {
+			t.Errorf("Task without an ID loaded with ID %d", task.ID)
+		}
+	}
+
+}
+
+func TestParseDocumentRejectsNewerVersions(t *testing.T) {
+
+	if _, err := ParseDocument(`{"Version": 1000, "Tasks": []}`, ""); err == nil || !strings.Contains(err.Error(), "newer version") {
+		t.Errorf("loading a project from a newer version returned %v", err)
+	}
+
+	if _, err := ParseDocument(`{"Version": 1}`, ""); err == nil {
+		t.Errorf("loading a project without Tasks didn't return an error")
+	}
+
+}
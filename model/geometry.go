@@ -0,0 +1,66 @@
+package model
+
+import "math"
+
+// Vector is a position or size in the world.
+type Vector struct {
+	X, Y float32
+}
+
+// Rect is an area of the world.
+type Rect struct {
+	X, Y, Width, Height float32
+}
+
+// Center returns the point in the middle of the Rect.
+func (rect Rect) Center() Vector {
+	return Vector{rect.X + rect.Width/2, rect.Y + rect.Height/2}
+}
+
+// Position is a cell of the grid Tasks are placed on.
+type Position struct {
+	X, Y int
+}
+
+// CellAt returns the grid cell the world position is in, for grid cells of the size given.
+func CellAt(x, y float32, gridSize int32) Position {
+	return Position{int(x / float32(gridSize)), int(y / float32(gridSize))}
+}
+
+// LockToGrid returns the position rounded to the nearest grid point, for grid cells of the size given.
+func LockToGrid(position Vector, gridSize int32) Vector {
+	gs := float32(gridSize)
+	return Vector{
+		float32(math.Round(float64(position.X/gs))) * gs,
+		float32(math.Round(float64(position.Y/gs))) * gs,
+	}
+}
+
+// PastePositions returns where Tasks taking up the areas given go when they're pasted at the target: they keep
+// their places relative to one another, with the middle of them all at the target, and each is locked to the grid.
+func PastePositions(bounds []Rect, target Vector, gridSize int32) []Vector {
+
+	positions := make([]Vector, len(bounds))
+
+	if len(bounds) == 0 {
+		return positions
+	}
+
+	center := Vector{}
+
+	for _, rect := range bounds {
+		c := rect.Center()
+		center.X += c.X
+		center.Y += c.Y
+	}
+
+	center.X /= float32(len(bounds))
+	center.Y /= float32(len(bounds))
+
+	for i, rect := range bounds {
+		positions[i] = LockToGrid(Vector{rect.X + target.X - center.X, rect.Y + target.Y - center.Y}, gridSize)
+	}
+
+	return positions
+
+}
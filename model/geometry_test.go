@@ -0,0 +1,50 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLockToGrid(t *testing.T) {
+
+	tests := []struct {
+		position Vector
+		want     Vector
+	}{
+		{Vector{0, 0}, Vector{0, 0}},
+		{Vector{7, 9}, Vector{0, 16}},
+		{Vector{24, -24}, Vector{32, -32}},
+		{Vector{-7, 40}, Vector{0, 48}},
+	}
+
+	for _, test := range tests {
+		if locked := LockToGrid(test.position, 16); locked != test.want {
+			t.Errorf("LockToGrid(%v) = %v, want %v", test.position, locked, test.want)
+		}
+	}
+
+}
+
+func TestPastePositions(t *testing.T) {
+
+	// Two 32x32 Tasks side by side, centered on (32, 16).
+	bounds := []Rect{{0, 0, 32, 32}, {32, 0, 32, 32}}
+
+	positions := PastePositions(bounds, Vector{160, 96}, 16)
+
+	if want := []Vector{{128, 80}, {160, 80}}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("got %v, want %v", positions, want)
+	}
+
+	// Each Task's locked to the grid on its own, so they keep their distance from one another.
+	positions = PastePositions(bounds, Vector{170, 101}, 16)
+
+	if want := []Vector{{144, 80}, {176, 80}}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("got %v, want %v", positions, want)
+	}
+
+	if positions := PastePositions(nil, Vector{160, 96}, 16); len(positions) != 0 {
+		t.Errorf("got %v for nothing to paste", positions)
+	}
+
+}
@@ -0,0 +1,136 @@
+package model
+
+import "math"
+
+// Grid is a spatial index of the things on a Board (that is, Tasks) by the grid cells they cover, so finding what's
+// at a spot doesn't mean going through everything on the Board. Things are kept in each cell in the order they were
+// added.
+type Grid struct {
+	cells     map[Position][]interface{}
+	positions map[interface{}][]Position
+}
+
+func NewGrid() *Grid {
+	return &Grid{
+		cells:     map[Position][]interface{}{},
+		positions: map[interface{}][]Position{},
+	}
+}
+
+// Add places the item in every cell the area covers, for grid cells of the size given. An item that's already in
+// the Grid is taken out of where it was first.
+func (grid *Grid) Add(item interface{}, bounds Rect, gridSize int32) {
+
+	grid.Remove(item)
+
+	gs := float32(gridSize)
+	startX, startY := int(bounds.X/gs), int(bounds.Y/gs)
+	endX, endY := int((bounds.X+bounds.Width)/gs), int((bounds.Y+bounds.Height)/gs)
+
+	positions := []Position{}
+
+	for y := startY; y < endY; y++ {
+		for x := startX; x < endX; x++ {
+			p := Position{x, y}
+			positions = append(positions, p)
+			grid.cells[p] = append(grid.cells[p], item)
+		}
+	}
+
+	grid.positions[item] = positions
+
+}
+
+// Remove takes the item out of the Grid.
+func (grid *Grid) Remove(item interface{}) {
+
+	for _, p := range grid.positions[item] {
+
+		items := grid.cells[p]
+
+		for i, other := range items {
+			if other == item {
+				items[i] = nil
+				items = append(items[:i], items[i+1:]...)
+				break
+			}
+		}
+
+		if len(items) == 0 {
+			delete(grid.cells, p)
+		} else {
+			grid.cells[p] = items
+		}
+
+	}
+
+	delete(grid.positions, item)
+
+}
+
+// At returns the items in the grid cell. The slice belongs to the Grid, so it shouldn't be changed.
+func (grid *Grid) At(cell Position) []interface{} {
+	return grid.cells[cell]
+}
+
+// Cells returns the grid cells the item is in.
+func (grid *Grid) Cells(item interface{}) []Position {
+	return grid.positions[item]
+}
+
+// Contains returns if the item is in the Grid.
+func (grid *Grid) Contains(item interface{}) bool {
+	_, exists := grid.positions[item]
+	return exists
+}
+
+// InRect returns the items in the cells the area touches, for grid cells of the size given, each only once.
+func (grid *Grid) InRect(bounds Rect, gridSize int32) []interface{} {
+
+	found := []interface{}{}
+	added := map[interface{}]bool{}
+
+	gs := float32(gridSize)
+
+	for cy := bounds.Y; cy < bounds.Y+bounds.Height; cy += gs {
+		for cx := bounds.X; cx < bounds.X+bounds.Width; cx += gs {
+			for _, item := range grid.cells[CellAt(cx, cy, gridSize)] {
+				if !added[item] {
+					found = append(found, item)
+					added[item] = true
+				}
+			}
+		}
+	}
+
+	return found
+
+}
+
+// Extent returns the lowest and highest grid cells that have anything in them, or false if the Grid's empty.
+func (grid *Grid) Extent() (Position, Position, bool) {
+
+	if len(grid.cells) == 0 {
+		return Position{}, Position{}, false
+	}
+
+	min, max := Position{math.MaxInt32, math.MaxInt32}, Position{math.MinInt32, math.MinInt32}
+
+	for p := range grid.cells {
+		if p.X < min.X {
+			min.X = p.X
+		}
+		if p.Y < min.Y {
+			min.Y = p.Y
+		}
+		if p.X > max.X {
+			max.X = p.X
+		}
+		if p.Y > max.Y {
+			max.Y = p.Y
+		}
+	}
+
+	return min, max, true
+
+}
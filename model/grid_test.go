@@ -0,0 +1,97 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGridAdd(t *testing.T) {
+
+	grid := NewGrid()
+
+	// A 32x16 Task at (16, 0) covers two 16x16 cells.
+	grid.Add("a", Rect{16, 0, 32, 16}, 16)
+
+	if cells := grid.Cells("a"); !reflect.DeepEqual(cells, []Position{{1, 0}, {2, 0}}) {
+		t.Errorf("got cells %v, want [{1 0} {2 0}]", cells)
+	}
+
+	if items := grid.At(Position{2, 0}); !reflect.DeepEqual(items, []interface{}{"a"}) {
+		t.Errorf("got %v at {2 0}, want [a]", items)
+	}
+
+	if items := grid.At(Position{0, 0}); len(items) != 0 {
+		t.Errorf("got %v at {0 0}, want nothing", items)
+	}
+
+	// Things in the same cell are kept in the order they were added.
+	grid.Add("b", Rect{32, 0, 16, 16}, 16)
+
+	if items := grid.At(Position{2, 0}); !reflect.DeepEqual(items, []interface{}{"a", "b"}) {
+		t.Errorf("got %v at {2 0}, want [a b]", items)
+	}
+
+}
+
+func TestGridMove(t *testing.T) {
+
+	grid := NewGrid()
+
+	grid.Add("a", Rect{0, 0, 16, 16}, 16)
+	grid.Add("a", Rect{64, 64, 16, 16}, 16)
+
+	if cells := grid.Cells("a"); !reflect.DeepEqual(cells, []Position{{4, 4}}) {
+		t.Errorf("got cells %v after moving, want [{4 4}]", cells)
+	}
+
+	if items := grid.At(Position{0, 0}); len(items) != 0 {
+		t.Errorf("got %v where the item used to be, want nothing", items)
+	}
+
+}
+
+func TestGridRemove(t *testing.T) {
+
+	grid := NewGrid()
+
+	grid.Add("a", Rect{0, 0, 32, 16}, 16)
+	grid.Add("b", Rect{16, 0, 16, 16}, 16)
+
+	grid.Remove("a")
+
+	if grid.Contains("a") {
+		t.Errorf("removed item is still in the grid")
+	}
+
+	if items := grid.At(Position{1, 0}); !reflect.DeepEqual(items, []interface{}{"b"}) {
+		t.Errorf("got %v at {1 0}, want [b]", items)
+	}
+
+	// Empty cells don't count towards the grid's extent.
+	min, max, ok := grid.Extent()
+	if !ok || min != (Position{1, 0}) || max != (Position{1, 0}) {
+		t.Errorf("got extent %v-%v (%t), want {1 0}-{1 0}", min, max, ok)
+	}
+
+	grid.Remove("b")
+
+	if _, _, ok := grid.Extent(); ok {
+		t.Errorf("empty grid has an extent")
+	}
+
+}
+
+func TestGridInRect(t *testing.T) {
+
+	grid := NewGrid()
+
+	grid.Add("a", Rect{0, 0, 48, 48}, 16)
+	grid.Add("b", Rect{32, 32, 16, 16}, 16)
+	grid.Add("c", Rect{128, 128, 16, 16}, 16)
+
+	// "a" covers every cell in the area, but it's only found once.
+	if items := grid.InRect(Rect{16, 16, 32, 32}, 16); !reflect.DeepEqual(items, []interface{}{"a", "b"}) {
+		t.Errorf("got %v, want [a b]", items)
+	}
+
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// A projectMigration upgrades the JSON data of a project file from one schema version to the next.
+type projectMigration func(data string) (string, error)
+
+// projectMigrations is the chain of migrations; the migration at index N upgrades a project from version N
+// to version N+1, so every version up to FileVersion needs to have one here.
+var projectMigrations = []projectMigration{
+	migrateFromMasterPlan, // 0 -> 1
+}
+
+// ProjectFileVersion returns the schema version of the project data. Original MasterPlan projects store the
+// software's semantic version as a string (e.g. "0.6.0"); those are all version 0.
+func ProjectFileVersion(data string) (int, error) {
+
+	version := gjson.Get(data, `Version`)
+
+	switch version.Type {
+
+	case gjson.Number:
+		return int(version.Int()), nil
+
+	case gjson.String:
+		if _, err := semver.ParseTolerant(version.String()); err != nil {
+			return 0, fmt.Errorf("unrecognized project version \"%s\"", version.String())
+		}
+		return 0, nil
+
+	case gjson.Null:
+		return FileVersion, nil // Very early versions of this fork didn't write a version at all
+
+	}
+
+	return 0, fmt.Errorf("unrecognized project version %s", version.Raw)
+
+}
+
+// MigrateProject runs the project data through every migration needed to bring it up to the current version,
+// returning the upgraded data. Projects saved by newer versions of the program can't be loaded.
+func MigrateProject(data string) (string, error) {
+
+	version, err := ProjectFileVersion(data)
+	if err != nil {
+		return "", err
+	}
+
+	if version > FileVersion {
+		return "", fmt.Errorf("project is version %d, but only versions up to %d are supported; it was saved by a newer version of MasterPlan", version, FileVersion)
+	}
+
+	for ; version < FileVersion; version++ {
+
+		data, err = projectMigrations[version](data)
+		if err != nil {
+			return "", fmt.Errorf("could not upgrade project from version %d: %s", version, err.Error())
+		}
+
+		data, _ = sjson.Set(data, `Version`, version+1)
+
+	}
+
+	return data, nil
+
+}
+
+// Task types as they're numbered in original MasterPlan projects.
+const (
+	masterPlanTaskTypeCheckbox = iota
+	masterPlanTaskTypeProgression
+	masterPlanTaskTypeNote
+	masterPlanTaskTypeImage
+	masterPlanTaskTypeSound
+	masterPlanTaskTypeTimer
+	masterPlanTaskTypeLine
+	masterPlanTaskTypeMap
+	masterPlanTaskTypeWhiteboard
+	masterPlanTaskTypeTable
+)
+
+// The zoom levels original MasterPlan indexes into with the project's ZoomLevel.
+var masterPlanZoomLevels = []float32{0.25, 0.5, 1, 2, 3, 4}
+
+// migrateFromMasterPlan upgrades a project made by original MasterPlan, which numbers its Task types and stores
+// zoom as an index, to this fork's format. Task types this fork doesn't have become notes that keep as much of
+// the original Task's information as possible in their description. Checkbox, progression, image and sound Tasks
+// are stored the same way in both (FilePath included), so they only need their type renamed.
+func migrateFromMasterPlan(data string) (string, error) {
+
+	tasks := gjson.Get(data, `Tasks`)
+
+	if !tasks.IsArray() {
+		return "", fmt.Errorf("Tasks is not an array")
+	}
+
+	if zoomLevel := gjson.Get(data, `ZoomLevel`); zoomLevel.Exists() {
+
+		index := int(zoomLevel.Int())
+
+		if index < 0 {
+			index = 0
+		} else if index >= len(masterPlanZoomLevels) {
+			index = len(masterPlanZoomLevels) - 1
+		}
+
+		data, _ = sjson.Set(data, `Zoom`, masterPlanZoomLevels[index])
+		data, _ = sjson.Delete(data, `ZoomLevel`)
+
+	}
+
+	taskData := []string{}
+	droppedLines := 0
+
+	for _, task := range tasks.Array() {
+
+		taskJSON := task.Raw
+		taskType := task.Get(`TaskType\.CurrentChoice`)
+
+		if taskType.Type != gjson.Number {
+			// Already a named type, so there's nothing to change
+			taskData = append(taskData, taskJSON)
+			continue
+		}
+
+		description := task.Get(`Description`).String()
+		newType := TASK_TYPE_NOTE
+
+		switch int(taskType.Int()) {
+
+		case masterPlanTaskTypeCheckbox:
+			newType = TASK_TYPE_CHECKBOX
+
+		case masterPlanTaskTypeProgression:
+			newType = TASK_TYPE_PROGRESSION
+
+		case masterPlanTaskTypeImage:
+			newType = TASK_TYPE_IMAGE
+
+		case masterPlanTaskTypeSound:
+			newType = TASK_TYPE_SOUND
+
+		case masterPlanTaskTypeTimer:
+			if name := task.Get(`TimerName\.Text`).String(); name != "" {
+				description = name
+			}
+
+		case masterPlanTaskTypeLine:
+			// Lines only make sense with the Tasks they connect, which don't exist here.
+			droppedLines++
+			continue
+
+		case masterPlanTaskTypeMap, masterPlanTaskTypeWhiteboard, masterPlanTaskTypeTable:
+			// There's nothing that can show this data, but we keep the Task so its description isn't lost.
+
+		case masterPlanTaskTypeNote:
+
+		default:
+			return "", fmt.Errorf("unknown Task type %d", taskType.Int())
+
+		}
+
+		taskJSON, _ = sjson.Set(taskJSON, `TaskType\.CurrentChoice`, newType)
+		taskJSON, _ = sjson.Set(taskJSON, `Description`, description)
+
+		taskData = append(taskData, taskJSON)
+
+	}
+
+	if droppedLines > 0 {
+		log.Printf("Dropped %d line Task(s) while upgrading project from original MasterPlan.", droppedLines)
+	}
+
+	data, _ = sjson.SetRaw(data, `Tasks`, "["+strings.Join(taskData, ",")+"]")
+
+	return data, nil
+
+}
@@ -0,0 +1,39 @@
+// Package model is the part of MasterPlan's project model that doesn't need a window: the .plan file format and
+// its migrations, the grid Tasks are placed on, Task IDs, and the Viewport and Input the GUI provides to the rest
+// of the model. It doesn't depend on raylib, so it can be used (and tested) without a GL context.
+package model
+
+// FileVersion is the schema version of the project files written by this version of MasterPlan.
+const FileVersion = 1
+
+const (
+	TASK_TYPE_NOTE        = "note"
+	TASK_TYPE_IMAGE       = "image"
+	TASK_TYPE_CHECKBOX    = "checkbox"
+	TASK_TYPE_PROGRESSION = "progression"
+	TASK_TYPE_SOUND       = "sound"
+)
+
+// TaskTypes lists every Task type in the order they're presented to the user.
+var TaskTypes = []string{TASK_TYPE_CHECKBOX, TASK_TYPE_PROGRESSION, TASK_TYPE_NOTE, TASK_TYPE_IMAGE, TASK_TYPE_SOUND}
+
+// FirstFreeID returns the lowest Task ID that isn't one of the IDs in use. Already spent, but nonexistent IDs are
+// reused (i.e. create a task that has ID 4, then delete that and create a new one; it should have an ID of 4 so
+// that when VCS diff the project file, it just alters the relevant pieces of info to make the original Task #4 the
+// new Task #4).
+func FirstFreeID(usedIDs []int) int {
+
+	used := map[int]bool{}
+
+	for _, id := range usedIDs {
+		used[id] = true
+	}
+
+	id := 0
+	for used[id] {
+		id++
+	}
+
+	return id
+
+}
@@ -0,0 +1,25 @@
+package model
+
+import "testing"
+
+func TestFirstFreeID(t *testing.T) {
+
+	tests := []struct {
+		used []int
+		want int
+	}{
+		{nil, 0},
+		{[]int{0, 1, 2}, 3},
+		{[]int{2, 0, 1}, 3},
+		{[]int{0, 1, 3}, 2}, // The ID of a deleted Task is reused
+		{[]int{1, 2}, 0},
+		{[]int{0, 0, 1}, 2},
+	}
+
+	for _, test := range tests {
+		if id := FirstFreeID(test.used); id != test.want {
+			t.Errorf("FirstFreeID(%v) = %d, want %d", test.used, id, test.want)
+		}
+	}
+
+}
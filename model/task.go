@@ -0,0 +1,193 @@
+package model
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// The format Task creation times are stored in.
+const creationTimeFormat = `Jan 2 2006 15:04:05`
+
+// TaskData is a Task's changeable properties, as they're stored in a project file (and in the undo history, and on
+// the clipboard). Only the properties that matter for the Task's type are stored.
+type TaskData struct {
+	ID                 int // -1 when loaded from data that doesn't have one
+	BoardIndex         int
+	Position           Vector
+	DisplaySize        Vector
+	Description        string
+	FilePath           string // An absolute path or URL; it's stored relative to the project file when possible
+	Selected           bool
+	TaskType           string
+	CreationTime       time.Time
+	TextSize           float32
+	AnimationPaused    bool
+	Checked            bool
+	ProgressionCurrent int
+	ProgressionMax     int
+}
+
+func (data TaskData) Is(taskTypes ...string) bool {
+	for _, taskType := range taskTypes {
+		if data.TaskType == taskType {
+			return true
+		}
+	}
+	return false
+}
+
+// HasText returns if the Task's type shows its description.
+func (data TaskData) HasText() bool {
+	return data.Is(TASK_TYPE_NOTE, TASK_TYPE_CHECKBOX, TASK_TYPE_PROGRESSION)
+}
+
+// UsesMedia returns if the Task's type shows or plays a file.
+func (data TaskData) UsesMedia() bool {
+	return data.Is(TASK_TYPE_IMAGE, TASK_TYPE_SOUND)
+}
+
+// Resizeable returns if the Task's type can be resized, and so has a DisplaySize.
+func (data TaskData) Resizeable() bool {
+	return data.UsesMedia() || data.HasText()
+}
+
+// Serialize returns the Task data as a JSON object in a string. Local files are stored relative to the project
+// file given, if there is one.
+func (data TaskData) Serialize(projectPath string) string {
+
+	jsonData := "{}"
+
+	jsonData, _ = sjson.Set(jsonData, `ID`, data.ID)
+	jsonData, _ = sjson.Set(jsonData, `BoardIndex`, data.BoardIndex)
+	jsonData, _ = sjson.Set(jsonData, `Position\.X`, data.Position.X)
+	jsonData, _ = sjson.Set(jsonData, `Position\.Y`, data.Position.Y)
+
+	if data.Resizeable() {
+		jsonData, _ = sjson.Set(jsonData, `ImageDisplaySize\.X`, data.DisplaySize.X)
+		jsonData, _ = sjson.Set(jsonData, `ImageDisplaySize\.Y`, data.DisplaySize.Y)
+	}
+
+	jsonData, _ = sjson.Set(jsonData, `Description`, data.Description)
+
+	if data.UsesMedia() && data.FilePath != "" {
+		jsonData, _ = sjson.Set(jsonData, `FilePath`, SerializeFilePath(projectPath, data.FilePath))
+	}
+
+	jsonData, _ = sjson.Set(jsonData, `Selected`, data.Selected)
+
+	jsonData, _ = sjson.Set(jsonData, `TaskType\.CurrentChoice`, data.TaskType)
+
+	jsonData, _ = sjson.Set(jsonData, `CreationTime`, data.CreationTime.Format(creationTimeFormat))
+
+	if data.HasText() {
+		jsonData, _ = sjson.Set(jsonData, `TextSize`, data.TextSize)
+	}
+
+	if data.Is(TASK_TYPE_IMAGE) && data.AnimationPaused {
+		jsonData, _ = sjson.Set(jsonData, `AnimationPaused`, true)
+	}
+
+	if data.Is(TASK_TYPE_CHECKBOX) {
+		jsonData, _ = sjson.Set(jsonData, `Checkbox\.Checked`, data.Checked)
+	}
+
+	if data.Is(TASK_TYPE_PROGRESSION) {
+		jsonData, _ = sjson.Set(jsonData, `Progression\.Current`, data.ProgressionCurrent)
+		jsonData, _ = sjson.Set(jsonData, `Progression\.Max`, data.ProgressionMax)
+	}
+
+	return jsonData
+
+}
+
+// ParseTaskData reads Task data from a JSON object in a string, as written by TaskData.Serialize(). Relative file
+// paths are taken to be relative to the project file given.
+func ParseTaskData(jsonData string, projectPath string) TaskData {
+
+	parsed := gjson.Parse(jsonData)
+
+	// JSON encodes all numbers as 64-bit floats, so this saves us some visual ugliness.
+	getFloat := func(name string) float32 {
+		return float32(parsed.Get(name).Float())
+	}
+
+	data := TaskData{
+		ID:                 -1,
+		BoardIndex:         int(parsed.Get(`BoardIndex`).Int()),
+		Position:           Vector{getFloat(`Position\.X`), getFloat(`Position\.Y`)},
+		DisplaySize:        Vector{getFloat(`ImageDisplaySize\.X`), getFloat(`ImageDisplaySize\.Y`)},
+		Description:        parsed.Get(`Description`).String(),
+		Selected:           parsed.Get(`Selected`).Bool(),
+		TaskType:           parsed.Get(`TaskType\.CurrentChoice`).String(),
+		TextSize:           getFloat(`TextSize`),
+		AnimationPaused:    parsed.Get(`AnimationPaused`).Bool(),
+		Checked:            parsed.Get(`Checkbox\.Checked`).Bool(),
+		ProgressionCurrent: int(parsed.Get(`Progression\.Current`).Int()),
+		ProgressionMax:     int(parsed.Get(`Progression\.Max`).Int()),
+	}
+
+	if id := parsed.Get(`ID`); id.Exists() {
+		data.ID = int(id.Int())
+	}
+
+	if filePath := parsed.Get(`FilePath`); filePath.Exists() {
+		data.FilePath = DeserializeFilePath(projectPath, filePath)
+	}
+
+	if creationTime, err := time.Parse(creationTimeFormat, parsed.Get(`CreationTime`).String()); err == nil {
+		data.CreationTime = creationTime
+	}
+
+	return data
+
+}
+
+// SerializeFilePath returns how the file path is stored for the project file given: paths to local files are
+// stored as an array of path components relative to the project file, so the project can be moved along with its
+// files; anything else (e.g. a URL, or a path in a project that hasn't been saved) is stored as a plain string.
+func SerializeFilePath(projectPath string, filePath string) interface{} {
+
+	if projectPath != "" && !IsRemotePath(filePath) {
+		if relative, err := filepath.Rel(filepath.Dir(projectPath), filePath); err == nil {
+			return strings.Split(relative, string(filepath.Separator))
+		}
+	}
+
+	return filePath
+
+}
+
+// DeserializeFilePath returns the path a serialized FilePath points to (see SerializeFilePath()).
+func DeserializeFilePath(projectPath string, filePath gjson.Result) string {
+
+	if !filePath.IsArray() {
+		return filePath.String()
+	}
+
+	str := []string{}
+	for _, component := range filePath.Array() {
+		str = append(str, component.String())
+	}
+
+	// We need to go from the project file as the "root", as otherwise it will be relative
+	// to the current working directory (which is not ideal).
+	str = append([]string{filepath.Dir(projectPath)}, str...)
+	joinedElements := strings.Join(str, string(filepath.Separator))
+	abs, _ := filepath.Abs(joinedElements)
+
+	return abs
+
+}
+
+// IsRemotePath returns if the resource path points to something on the web rather than on disk.
+func IsRemotePath(resourcePath string) bool {
+	if parsed, err := url.Parse(resourcePath); err == nil {
+		return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package model
+
+// A Viewport tells the model where the user is looking and pointing in the world, so the model doesn't have to
+// reach into the window or camera itself. The GUI provides one looking through the window; without a window,
+// there's FixedViewport.
+type Viewport interface {
+	// WorldMousePosition returns the position of the mouse cursor in world coordinates.
+	WorldMousePosition() Vector
+	// Center returns the world position in the center of the view.
+	Center() Vector
+	// VisibleRect returns the area of the world that's currently in view.
+	VisibleRect() Rect
+}
+
+// Input is what the model reads of the user's mouse and keyboard input for the current frame. The GUI provides
+// one reading the window's input; without a window, there's NoInput.
+type Input interface {
+	MousePressed(button int32) bool
+	MouseDown(button int32) bool
+	MouseReleased(button int32) bool
+	// BindingOn returns if the named keybinding is held down.
+	BindingOn(bindingName string) bool
+	// DroppedFiles returns the paths of files dropped onto the window this frame.
+	DroppedFiles() []string
+}
+
+// FixedViewport is a Viewport that stays put, with the mouse resting in its center.
+type FixedViewport struct {
+	Position Vector
+	Size     Vector
+}
+
+func (viewport *FixedViewport) WorldMousePosition() Vector {
+	return viewport.Position
+}
+
+func (viewport *FixedViewport) Center() Vector {
+	return viewport.Position
+}
+
+func (viewport *FixedViewport) VisibleRect() Rect {
+	return Rect{viewport.Position.X - (viewport.Size.X / 2), viewport.Position.Y - (viewport.Size.Y / 2), viewport.Size.X, viewport.Size.Y}
+}
+
+// NoInput is the Input of a Project with no user in front of it; nothing is ever pressed.
+type NoInput struct{}
+
+func (input NoInput) MousePressed(button int32) bool    { return false }
+func (input NoInput) MouseDown(button int32) bool       { return false }
+func (input NoInput) MouseReleased(button int32) bool   { return false }
+func (input NoInput) BindingOn(bindingName string) bool { return false }
+func (input NoInput) DroppedFiles() []string            { return nil }
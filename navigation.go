@@ -0,0 +1,235 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/solarlune/masterplan/model"
+)
+
+// Directions for moving around a Board with the keyboard, in grid cells.
+var (
+	DirectionUp    = model.Position{0, -1}
+	DirectionRight = model.Position{1, 0}
+	DirectionDown  = model.Position{0, 1}
+	DirectionLeft  = model.Position{-1, 0}
+)
+
+func taskCenter(task *Task) rl.Vector2 {
+	return rl.Vector2{task.Position.X + task.Rect.Width/2, task.Position.Y + task.Rect.Height/2}
+}
+
+// navigationOrigin returns the Task keyboard navigation starts from: the first selected Task or, if none are
+// selected, the Task closest to the center of the view.
+func (board *Board) navigationOrigin() *Task {
+
+	if selected := board.SelectedTasks(true); len(selected) > 0 {
+		return selected[0]
+	}
+
+	center := rlVector(board.Project.Viewport.Center())
+
+	var closest *Task
+	closestDistance := float32(math.MaxFloat32)
+
+	for _, task := range board.Tasks {
+		if distance := rl.Vector2Distance(center, taskCenter(task)); distance < closestDistance {
+			closest = task
+			closestDistance = distance
+		}
+	}
+
+	return closest
+
+}
+
+// FindTaskInDirection returns the nearest Task in the direction from the given Task, or nil if there isn't one.
+// It looks through the TaskLocations grid ring by ring in a cone spreading out in the direction; Tasks that are
+// more in line with the direction win out over ones that are a bit closer but off to the side.
+func (board *Board) FindTaskInDirection(from *Task, direction model.Position) *Task {
+
+	// How far there is to look at most is how far the grid goes.
+	minCell, maxCell, ok := board.TaskLocations.Extent()
+	if !ok {
+		return nil
+	}
+
+	origin := taskCenter(from)
+	startX, startY := board.Project.WorldToGrid(origin.X, origin.Y)
+	maxDistance := maxInt(maxInt(absInt(startX-minCell.X), absInt(startX-maxCell.X)), maxInt(absInt(startY-minCell.Y), absInt(startY-maxCell.Y)))
+
+	gs := float32(board.Project.GridSize)
+
+	var best *Task
+	bestScore := float32(math.MaxFloat32)
+
+	for distance := 1; distance <= maxDistance; distance++ {
+
+		// Tasks further out can't score better than the best one found so far.
+		if best != nil && float32(distance-1)*gs > bestScore {
+			break
+		}
+
+		for side := -distance; side <= distance; side++ {
+
+			// Going sideways from the direction is going along the other axis.
+			cell := model.Position{
+				startX + direction.X*distance + direction.Y*side,
+				startY + direction.Y*distance + direction.X*side,
+			}
+
+			for _, task := range gridTasks(board.TaskLocations.At(cell)) {
+
+				if task == from {
+					continue
+				}
+
+				offset := rl.Vector2Subtract(taskCenter(task), origin)
+				along := offset.X*float32(direction.X) + offset.Y*float32(direction.Y)
+				across := float32(math.Abs(float64(offset.X*float32(direction.Y) + offset.Y*float32(direction.X))))
+
+				if along <= 0 {
+					continue // Overlapping Tasks can reach back past the one we're coming from
+				}
+
+				if score := along + across*2; score < bestScore {
+					best = task
+					bestScore = score
+				}
+
+			}
+
+		}
+
+	}
+
+	return best
+
+}
+
+// SelectTaskInDirection selects the nearest Task in the direction from the current selection, moving the view to it
+// if it's out of sight.
+func (board *Board) SelectTaskInDirection(direction model.Position) {
+
+	from := board.navigationOrigin()
+	if from == nil {
+		return
+	}
+
+	target := from
+
+	// Without a selection, the Task closest to the center of the view is where we start from, so it's selected first.
+	if from.Selected {
+		target = board.FindTaskInDirection(from, direction)
+	}
+
+	if target != nil {
+		board.selectAndShow(target)
+	}
+
+}
+
+// SlideSelectedTasks moves the selected Tasks a grid cell in the direction, skipping past any Tasks in the way.
+func (board *Board) SlideSelectedTasks(direction model.Position) {
+
+	selected := board.SelectedTasks(false)
+
+	// The Tasks furthest along in the direction move first, so the ones behind them can move into the space they left.
+	sort.SliceStable(selected, func(i, j int) bool {
+		a, b := taskCenter(selected[i]), taskCenter(selected[j])
+		return a.X*float32(direction.X)+a.Y*float32(direction.Y) > b.X*float32(direction.X)+b.Y*float32(direction.Y)
+	})
+
+	gs := float32(board.Project.GridSize)
+
+	for _, task := range selected {
+		task.Move(float32(direction.X)*gs, float32(direction.Y)*gs)
+		task.ReceiveMessage(MessageDropped, nil)
+	}
+
+	if len(selected) > 0 && !rl.CheckCollisionRecs(selected[0].Rect, rlRect(board.Project.Viewport.VisibleRect())) {
+		board.FocusViewOnSelectedTasks()
+	}
+
+}
+
+// Stack returns the Tasks overlapping the grid cell at the Task's top-left corner, from the bottom to the top of
+// the stack (that is, in the order they're drawn).
+func (board *Board) Stack(task *Task) []*Task {
+
+	cx, cy := board.Project.WorldToGrid(task.Position.X, task.Position.Y)
+	stack := gridTasks(board.TaskLocations.At(model.Position{cx, cy}))
+
+	sort.SliceStable(stack, func(i, j int) bool {
+		if stack[i].Position.Y == stack[j].Position.Y {
+			return stack[i].Position.X < stack[j].Position.X
+		}
+		return stack[i].Position.Y < stack[j].Position.Y
+	})
+
+	return stack
+
+}
+
+// CycleStack selects the next Task up (for positive directions) or down the stack the selected Task is in, wrapping
+// around at the top and bottom.
+func (board *Board) CycleStack(direction int) {
+
+	from := board.navigationOrigin()
+	if from == nil {
+		return
+	}
+
+	stack := board.Stack(from)
+
+	for i, task := range stack {
+		if task == from {
+			board.selectAndShow(stack[((i+direction)%len(stack)+len(stack))%len(stack)])
+			return
+		}
+	}
+
+}
+
+func (board *Board) selectAndShow(task *Task) {
+
+	board.Project.SendMessage(MessageSelect, map[string]interface{}{"task": task})
+
+	if !rl.CheckCollisionRecs(task.Rect, rlRect(board.Project.Viewport.VisibleRect())) {
+		board.FocusViewOnSelectedTasks()
+	}
+
+}
+
+// arrowKeyDirection returns the direction of the task selection keybinding that's pressed, if one is.
+func arrowKeyDirection(keybindings *Keybindings) (model.Position, bool) {
+
+	switch {
+	case keybindings.On(KBSelectTaskAbove):
+		return DirectionUp, true
+	case keybindings.On(KBSelectTaskRight):
+		return DirectionRight, true
+	case keybindings.On(KBSelectTaskBelow):
+		return DirectionDown, true
+	case keybindings.On(KBSelectTaskLeft):
+		return DirectionLeft, true
+	}
+
+	return model.Position{}, false
+
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
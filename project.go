@@ -2,28 +2,22 @@ package main
 
 import (
 	"fmt"
-	"image/gif"
-	"io"
 	"io/ioutil"
 	"log"
-	"math"
-	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/goware/urlx"
 	"github.com/tanema/gween"
 	"github.com/tanema/gween/ease"
-	"github.com/tidwall/gjson"
-	"github.com/tidwall/sjson"
 
+	"github.com/inkyblackness/imgui-go/v3"
 	"github.com/ncruces/zenity"
 
-	"github.com/gabriel-vasile/mimetype"
 	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/solarlune/masterplan/model"
 )
 
 const (
@@ -41,6 +35,7 @@ const (
 
 	ActionNewProject    = "new"
 	ActionLoadProject   = "load"
+	ActionOpenBundle    = "open bundle"
 	ActionSaveAsProject = "save as"
 	ActionRenameBoard   = "rename"
 	ActionQuit          = "quit"
@@ -48,7 +43,6 @@ const (
 	BackupDelineator = "_bak_"
 )
 
-var firstFreeTaskID = 0
 
 type Project struct {
 
@@ -58,9 +52,24 @@ type Project struct {
 	Boards              []*Board
 	BoardIndex          int
 	BoardPanel          rl.Rectangle
+	BoardPanelOpen      bool
+
+	// The Board panel's rename and delete prompts
+	RenameBoardText       string
+	OpenRenameBoardPrompt bool
+	DeleteBoardPrompt     *Board
+
+	// Board navigation; ViewBoard is the Board whose view the camera is showing.
+	BoardHistory          []*Board
+	BoardHistoryIndex     int
+	ViewBoard             *Board
+	BoardPickerFilter     string
+	OpenBoardPickerPrompt bool
+
   Zoom                float32
 	CameraPan           rl.Vector2
 	CameraOffset        rl.Vector2
+	CameraZoom          float32 // The zoom level being shown; Zoom is the level the camera's heading to
 	FullyInitialized    bool
 	ContextMenuOpen     bool
 	ContextMenuPosition rl.Vector2
@@ -70,23 +79,49 @@ type Project struct {
 	DoubleClickTimer    time.Time
 	DoubleClickTaskID   int
 	CopyBuffer          []*Task
+	ClipboardTasks      string // The CopyBuffer's Tasks as they were put on the clipboard, to tell them apart from others' there
 	Cutting             bool // If cutting, then this boolean is set
 	TaskOpen            bool
 	JustLoaded          bool
 	ResizingImage       bool
 	LogOn               bool
+	BackupsOpen         bool
+	UnusedAssetsOpen    bool
+	UnusedAssetList     []string // The last list of unused assets found, for the Unused Assets window
+	UnusedAssetsError   error    // Why the last list of unused assets couldn't be found, if it couldn't
+	DeleteAssetsPrompt  []string // The unused assets waiting on the user to confirm deleting them
+	LastBackup          time.Time
+	LastBackupData      string
 
 	ShortcutKeyTimer  int
 	PreviousTaskType  string
 	Resources         map[string]*Resource
+	resourceMutex     sync.Mutex      // Guards Resources and loadedResources, as resources are loaded in the background
+	loadedResources   []*resourceLoad // Resources that have been decoded and are waiting to be uploaded
+	resourcesClosed   bool
+	Viewport          model.Viewport
+	Input             model.Input
 	Modified          bool
-
+	ModifiedTime      time.Time
+	ModifiedCount     int // How many times the Project's been changed, so what's worked out from it knows when it's outdated
+
+	// The destructive action waiting on the user to decide what to do with unsaved changes
+	PendingAction            string
+	PendingActionArgument    string
+	OpenUnsavedChangesPrompt bool
+
+	UndoHistory   *UndoHistory
+	Search        *Search
+	Minimap       *Minimap
+	Exporter      *Exporter
 	UndoFade      *gween.Sequence
 	Undoing       int
 	TaskEditRect  rl.Rectangle
 }
 
-func NewProject() *Project {
+// NewProject returns an empty Project, which sees where the user's looking and pointing through the Viewport and
+// reads their input through the Input given.
+func NewProject(viewport model.Viewport, input model.Input) *Project {
 
 	project := &Project{
 		FilePath: "",
@@ -94,10 +129,19 @@ func NewProject() *Project {
     Zoom: 1.0,
 		CameraPan: rl.Vector2{0, 0},
 		Resources: map[string]*Resource{},
+		Viewport: viewport,
+		Input: input,
+		LastBackup: time.Now(),
+		BoardPanelOpen: true,
 	}
 
   project.Boards = []*Board{NewBoard(project)}
 
+	project.UndoHistory = NewUndoHistory(project)
+	project.Search = NewSearch(project)
+	project.Minimap = NewMinimap(project)
+	project.Exporter = NewExporter(project)
+
 	return project
 }
 
@@ -130,91 +174,124 @@ func (project *Project) SaveAs() {
 
 }
 
-func (project *Project) Save(backup bool) {
+// Serialize returns the Project as the JSON that goes in its .plan file.
+func (project *Project) Serialize() string {
 
-	success := true
+  project.updateBoardView()
+  project.CurrentBoard().Pan = project.CameraPan
+  project.CurrentBoard().Zoom = project.Zoom
 
-  if project.FilePath != "" {
+  document := &model.Document{
+    Version:    softwareVersion,
+    BoardIndex: project.BoardIndex,
+    Pan:        modelVector(project.CameraPan),
+    Zoom:       project.Zoom,
+    ColorTheme: currentTheme,
+    GridSize:   project.GridSize,
+  }
 
-    // Sort the Tasks by their ID, then loop through them using that slice. This way,
-    // They store data according to their creation ID, not according to their position
-    // in the world.
-    tasksByID := append([]*Task{}, project.GetAllTasks()...)
+  for _, board := range project.Boards {
 
-    sort.Slice(tasksByID, func(i, j int) bool { return tasksByID[i].ID < tasksByID[j].ID })
+    document.Boards = append(document.Boards, model.BoardData{Name: board.Name, Pan: modelVector(board.Pan), Zoom: board.Zoom})
 
-    // We're passing in actual JSON strings for task serlizations, so we have to actually construct the
-    // string containing our JSON array of tasks ourselves.
-    taskData := "["
-    firstTask := true
-    for _, task := range tasksByID {
-      if firstTask {
-        firstTask = false
-      } else {
-        taskData += ","
-      }
-      if task.Serializable() {
-        taskData += task.Serialize()
-      }
+    for _, connection := range board.Connections {
+      document.Connections = append(document.Connections, connection.Data())
     }
-    taskData += "]"
-
-    data := `{}`
-
-    // Not handling any of these errors because uuuuuuuuuh idkkkkkk should there ever really be errors
-    // with a blank JSON {} object????
-    data, _ = sjson.Set(data, `Version`, softwareVersion)
-    data, _ = sjson.Set(data, `BoardIndex`, project.BoardIndex)
-    data, _ = sjson.Set(data, `BoardCount`, len(project.Boards))
-    data, _ = sjson.Set(data, `Pan\.X`, project.CameraPan.X)
-    data, _ = sjson.Set(data, `Pan\.Y`, project.CameraPan.Y)
-    data, _ = sjson.Set(data, `Zoom`, project.Zoom)
-    data, _ = sjson.Set(data, `ColorTheme`, currentTheme)
-    data, _ = sjson.Set(data, `GridSize`, project.GridSize)
-
-    boardNames := []string{}
-    for _, board := range project.Boards {
-      boardNames = append(boardNames, board.Name)
+
+  }
+
+  for _, task := range project.GetAllTasks() {
+    if task.Serializable() {
+      document.Tasks = append(document.Tasks, task.Data())
     }
-    data, _ = sjson.Set(data, `BoardNames`, boardNames)
+  }
 
-    data, _ = sjson.SetRaw(data, `Tasks`, taskData) // taskData is already properly encoded and formatted JSON
+  return document.Serialize(project.FilePath)
 
-    f, err := os.Create(project.FilePath)
-    if err != nil {
-      project.Log("Error in creating save file: ", err.Error())
-    } else {
-      defer f.Close()
+}
 
-      data = gjson.Parse(data).Get("@pretty").String() // Pretty print it so it's visually nice in the .plan file.
+// Save writes the Project to its file, or to a new backup of it, returning why it couldn't if it couldn't.
+func (project *Project) Save(backup bool) error {
 
-      f.Write([]byte(data))
-      programSettings.Save()
+  if project.FilePath == "" {
+    project.Log("ERROR: Save / backup unsuccessful; the project hasn't been given a file to save to.")
+    return fmt.Errorf("the project hasn't been given a file to save to")
+  }
 
-      err = f.Sync() // Want to make sure the file is written
-      if err != nil {
-        project.Log("ERROR: Can't write file to system: ", err.Error())
-        success = false
-      }
+  // Anything added before the Project had anywhere to put it goes into its assets folder now that it does. That's
+  // left to the user when running headless, as it moves files around outside of the Project.
+  if !backup && !headless {
+    project.importTemporaryAssets()
+  }
 
-    }
+  data := project.Serialize()
 
-  } else {
-    success = false
+  if backup && data == project.LastBackupData {
+    // Nothing's changed since the last backup, so there's no point in rotating out an older one for it.
+    return nil
   }
 
-  if success {
-    if !backup {
-      // Modified flag only gets cleared on manual saves, not automatic backups
-      project.Modified = false
-    }
-  } else {
+  savePath := project.FilePath
+  if backup {
+    savePath = project.BackupPath(time.Now())
+  }
+
+  // The data is written to a temporary file first and then moved over the real one, so a crash or full disk
+  // partway through can't leave a truncated project behind.
+  if err := WriteFileAtomically(savePath, []byte(data)); err != nil {
+    project.Log("ERROR: Can't write file to system: %s", err.Error())
     project.Log("ERROR: Save / backup unsuccessful.")
+    return err
   }
 
+  if backup {
+    project.LastBackupData = data
+    project.PruneBackups()
+  } else {
+    // Modified flag only gets cleared on manual saves, not automatic backups
+    project.Modified = false
+    // The program's settings are the GUI's; running headless shouldn't touch them.
+    if !headless {
+      programSettings.Save()
+    }
+  }
+
+  return nil
+
 }
 
-func LoadProjectFrom() *Project {
+// MarkModified flags the Project as having unsaved changes.
+func (project *Project) MarkModified() {
+	project.Modified = true
+	project.ModifiedTime = time.Now()
+	project.ModifiedCount++
+}
+
+// HandleAutosave saves the Project once it's had unsaved changes and gone untouched for the autosave idle period.
+func (project *Project) HandleAutosave() {
+
+	idle := time.Duration(programSettings.AutoSaveIdleSeconds) * time.Second
+
+	if !programSettings.AutoSave || !project.Modified || project.FilePath == "" || project.ResizingImage {
+		return
+	}
+
+	for _, task := range project.CurrentBoard().Tasks {
+		if task.Dragging {
+			return // Still being worked on
+		}
+	}
+
+	if time.Since(project.ModifiedTime) >= idle {
+		// If saving fails, this keeps us from trying again every frame until the next idle period passes.
+		project.ModifiedTime = time.Now()
+		project.Save(false)
+	}
+
+}
+
+// LoadProjectFrom asks for a project file to load, and loads it (see LoadProject()).
+func LoadProjectFrom(viewport model.Viewport, input model.Input) *Project {
 
 	// I used to have the extension for this file selector set to "*.plan", but Mac doesn't seem to recognize
 	// MasterPlan's .plan files as having that extension, using both dlgs and zenity. Not sure why; filters work when loading
@@ -222,7 +299,7 @@ func LoadProjectFrom() *Project {
 	// JSON files; that's what they are, anyway...
 
 	if file, err := zenity.SelectFile(zenity.Title("Select MasterPlan Project File")); err == nil && file != "" {
-		if loadedProject := LoadProject(file); loadedProject != nil {
+		if loadedProject := LoadProject(file, viewport, input); loadedProject != nil {
 			return loadedProject
 		}
 	}
@@ -231,142 +308,153 @@ func LoadProjectFrom() *Project {
 
 }
 
-func LoadProject(filepath string) *Project {
+// LoadProject loads the project file, returning nil if it couldn't be loaded. The Project sees and reads input
+// through the Viewport and Input given (see NewProject()).
+func LoadProject(filepath string, viewport model.Viewport, input model.Input) *Project {
 
-	project := NewProject()
+	fileData, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		logLoadError("Error: Could not load plan:\n[ %s ]: %s", filepath, err.Error())
+		return nil
+	}
 
-	if fileData, err := ioutil.ReadFile(filepath); err == nil {
+	project := NewProject(viewport, input)
 
-		data := gjson.Parse(string(fileData))
+	if original, _, isBackup := parseBackupPath(filepath); isBackup {
+		// Loading a backup opens it as the project it was made from, so saving overwrites the original.
+		project.FilePath = original
+		project.Log("Restored backup [ %s ].", filepath)
+	} else {
+		project.FilePath = filepath
+	}
 
-		if data.Get("Tasks").Exists() {
+	// Older projects (including ones from original MasterPlan) are upgraded to the current format before loading
+	document, err := model.ParseDocument(string(fileData), project.FilePath)
+	if err != nil {
+		logLoadError("Error: Could not load plan:\n[ %s ]: %s", filepath, err.Error())
+		return nil
+	}
 
-			project.JustLoaded = true
+	project.JustLoaded = true
 
-			if strings.Contains(filepath, BackupDelineator) {
-				project.FilePath = strings.Split(filepath, BackupDelineator)[0]
-			} else {
-				project.FilePath = filepath
-			}
+	project.GridSize = document.GridSize
+	project.CameraPan = rlVector(document.Pan)
+	project.Zoom = document.Zoom
 
-			getFloat := func(name string) float32 {
-				return float32(data.Get(name).Float())
-			}
+	project.LogOn = false
 
-			getInt := func(name string) int {
-				return int(data.Get(name).Int())
-			}
+	for i := 0; i < len(document.Boards)-1; i++ {
+		project.AddBoard()
+	}
 
-			//getString := func(name string) string {
-			//	return data.Get(name).String()
-			//}
+	for i, board := range project.Boards {
+		board.Name = document.Boards[i].Name
+		board.Pan = rlVector(document.Boards[i].Pan)
+		board.Zoom = document.Boards[i].Zoom
+	}
 
-			//getBool := func(name string) bool {
-			//	return data.Get(name).Bool()
-			//}
+	if boardIndex := document.BoardIndex; boardIndex >= 0 && boardIndex < len(project.Boards) {
+		project.BoardIndex = boardIndex
+	}
 
-			project.GridSize = int32(getInt(`GridSize`))
-			project.CameraPan.X = getFloat(`Pan\.X`)
-			project.CameraPan.Y = getFloat(`Pan\.Y`)
-			project.Zoom = getFloat(`Zoom`)
+	// The Project's own view is the current Board's.
+	project.ViewBoard = project.CurrentBoard()
 
-			project.LogOn = false
+	tasksByID := map[int]*Task{}
 
-			boardNames := []string{}
-			for _, name := range data.Get(`BoardNames`).Array() {
-				boardNames = append(boardNames, name.String())
-			}
+	for _, taskData := range document.Tasks {
 
-			for i := 0; i < getInt(`BoardCount`)-1; i++ {
-				project.AddBoard()
-			}
+		boardIndex := taskData.BoardIndex
 
-			for i := range project.Boards {
-				if i < len(boardNames) {
-					project.Boards[i].Name = boardNames[i]
-				}
-			}
+		// Tasks on Boards that don't exist end up on the last one rather than crashing the load.
+		if boardIndex < 0 || boardIndex >= len(project.Boards) {
+			logLoadError("Task on nonexistent Board %d moved to Board %d.", boardIndex, len(project.Boards))
+			boardIndex = len(project.Boards) - 1
+		}
 
-			for _, taskData := range data.Get(`Tasks`).Array() {
+		task := project.Boards[boardIndex].CreateNewTask()
+		task.applyData(taskData)
 
-				boardIndex := 0
+		// A mangled file could have Tasks sharing an ID; only the first one keeps it.
+		if tasksByID[task.ID] != nil {
+			task.ID = project.FirstFreeID()
+		}
 
-				if taskData.Get(`BoardIndex`).Exists() {
-					boardIndex = int(taskData.Get(`BoardIndex`).Int())
-				}
+		tasksByID[task.ID] = task
+	}
 
-				task := project.Boards[boardIndex].CreateNewTask()
-				task.Deserialize(taskData.String())
-			}
+	for _, connectionData := range document.Connections {
+		if connection := ConnectionFromData(connectionData, tasksByID); connection != nil {
+			connection.Start.Board.Connections = append(connection.Start.Board.Connections, connection)
+		}
+	}
 
-			project.LogOn = true
+	project.LogOn = true
 
-			list := []string{}
+	list := []string{}
 
-			existsInList := func(value string) bool {
-				for _, item := range list {
-					if value == item {
-						return true
-					}
-				}
-				return false
+	existsInList := func(value string) bool {
+		for _, item := range list {
+			if value == item {
+				return true
 			}
+		}
+		return false
+	}
 
-			lastOpenedIndex := -1
-			i := 0
-			for _, s := range programSettings.RecentPlanList {
-				_, err := os.Stat(s)
-				if err == nil && !existsInList(s) {
-					// If err != nil, the file must not exist, so we'll skip it
-					list = append(list, s)
-					if s == filepath {
-						lastOpenedIndex = i
-					}
-					i++
-				}
+	lastOpenedIndex := -1
+	i := 0
+	for _, s := range programSettings.RecentPlanList {
+		_, err := os.Stat(s)
+		if err == nil && !existsInList(s) {
+			// If err != nil, the file must not exist, so we'll skip it
+			list = append(list, s)
+			if s == project.FilePath {
+				lastOpenedIndex = i
 			}
+			i++
+		}
+	}
 
-			if lastOpenedIndex > 0 {
-
-				// If the project to be opened is already in the recent files list, then we can just bump it up to the front.
-
-				// ABC <- Say we want to move B to the front.
-
-				// list = ABC_
-				list = append(list, "")
-
-				// list = AABC
-				copy(list[1:], list[0:])
-
-				// list = BABC
-				list[0] = list[lastOpenedIndex+1] // Index needs to be +1 here because we made the list 1 larger above
+	if lastOpenedIndex > 0 {
 
-				// list = BAC
-				list = append(list[:lastOpenedIndex+1], list[lastOpenedIndex+2:]...)
+		// If the project to be opened is already in the recent files list, then we can just bump it up to the front.
 
-			} else if lastOpenedIndex < 0 {
-				list = append([]string{filepath}, list...)
-			}
+		// ABC <- Say we want to move B to the front.
 
-			programSettings.RecentPlanList = list
+		// list = ABC_
+		list = append(list, "")
 
-			programSettings.Save()
+		// list = AABC
+		copy(list[1:], list[0:])
 
-			return project
+		// list = BABC
+		list[0] = list[lastOpenedIndex+1] // Index needs to be +1 here because we made the list 1 larger above
 
-		}
+		// list = BAC
+		list = append(list[:lastOpenedIndex+1], list[lastOpenedIndex+2:]...)
 
+	} else if lastOpenedIndex < 0 {
+		list = append([]string{project.FilePath}, list...)
 	}
 
-	// It's possible for the file to be mangled and unable to be loaded; I should actually handle this
-	// with a backup system or something.
-
-	// We log on the current project because this project didn't load correctly
+	// Projects opened from the command line aren't ones the user opened, so they don't count as recent.
+	if !headless {
+		programSettings.RecentPlanList = list
+		programSettings.Save()
+	}
 
-	currentProject.Log("Error: Could not load plan:\n[ %s ].", filepath)
+	return project
 
-	return nil
+}
 
+// logLoadError logs on the current project if there is one (there isn't when loading from the command line).
+func logLoadError(text string, variables ...interface{}) {
+	if currentProject != nil {
+		currentProject.Log(text, variables...)
+	} else {
+		log.Printf(text, variables...)
+	}
 }
 
 func (project *Project) Log(text string, variables ...interface{}) {
@@ -384,37 +472,30 @@ func (project *Project) Log(text string, variables ...interface{}) {
 
 func (project *Project) HandleCamera() {
 
-	wheel := rl.GetMouseWheelMove()
-
+	project.updateBoardView()
 
-  if !project.TaskOpen && !project.ProjectSettingsOpen {
-    zoom_dx := float32(0.0)
+	wheel := rl.GetMouseWheelMove()
 
+	if wheel != 0 && project.MousingOver() == "Project" && !project.ProjectSettingsOpen {
 		if wheel > 0 {
-      zoom_dx = 1.0
-		} else if wheel < 0 {
-      zoom_dx = -1.0
+			project.ZoomAt(project.Zoom*1.1, GetMousePosition())
+		} else {
+			project.ZoomAt(project.Zoom/1.1, GetMousePosition())
 		}
-
-    if zoom_dx != 0 {
-      project.Zoom += project.Zoom * 0.1 * zoom_dx;
-    }
 	}
 
-  if project.Zoom < 0.0001 {
-    project.Zoom = 0.0001
-  }
-
-	camera.Zoom = project.Zoom
-
 	if MouseDown(rl.MouseMiddleButton) {
+		// Dragging the view around should follow the mouse exactly, rather than easing after it.
 		diff := GetMouseDelta()
 		project.CameraPan.X += diff.X
 		project.CameraPan.Y += diff.Y
+		project.CameraOffset.X += diff.X
+		project.CameraOffset.Y += diff.Y
 	}
 
-	project.CameraOffset.X += float32(project.CameraPan.X-project.CameraOffset.X)
-	project.CameraOffset.Y += float32(project.CameraPan.Y-project.CameraOffset.Y)
+	project.updateCameraAnimation()
+
+	camera.Zoom = project.CameraZoom
 
 	camera.Target.X = float32(-project.CameraOffset.X)
 	camera.Target.Y = float32(-project.CameraOffset.Y)
@@ -425,8 +506,12 @@ func (project *Project) HandleCamera() {
 
 func (project *Project) MousingOver() string {
 
-	if rl.CheckCollisionPointRec(GetMousePosition(), project.BoardPanel) {
+	if imgui.CurrentIO().WantCaptureMouse() {
+		return "GUI"
+	} else if rl.CheckCollisionPointRec(GetMousePosition(), project.BoardPanel) {
 		return "Boards"
+	} else if project.Minimap.Visible() && rl.CheckCollisionPointRec(GetMousePosition(), project.Minimap.Rect()) {
+		return "Minimap"
 	} else if project.TaskOpen {
 		return "TaskOpen"
 	} else {
@@ -446,6 +531,11 @@ func (project *Project) Update() {
 
 	selectionRect := rl.Rectangle{}
 
+	project.HandleLoadedResources()
+
+	// The minimap goes first, so clicking on it doesn't also click on the Tasks underneath it.
+	project.Minimap.Update()
+
 	for _, task := range project.GetAllTasks() {
 		task.Update()
 	}
@@ -462,10 +552,15 @@ func (project *Project) Update() {
 		return sorted[i].Depth() < sorted[j].Depth()
 	})
 
+	project.CurrentBoard().UpdateHierarchy()
+	project.CurrentBoard().DrawConnections()
+
 	for _, task := range sorted {
 		task.Draw()
 	}
 
+	project.Search.DrawHighlights()
+
 	project.HandleCamera()
 
 	if !project.TaskOpen {
@@ -630,6 +725,10 @@ func (project *Project) Update() {
 
 	project.Shortcuts()
 
+	project.HandleBackups()
+
+	project.HandleAutosave()
+
 	if project.JustLoaded {
 
 		for _, t := range project.GetAllTasks() {
@@ -641,13 +740,19 @@ func (project *Project) Update() {
 			board.ReorderTasks()
 		}
 
-		project.Modified = false
+		// A Project that isn't saved anywhere (like one opened from a bundle) only exists in memory, so it stays
+		// unsaved; otherwise, nothing would stop it being closed without being saved.
+		project.Modified = project.FilePath == ""
 		project.JustLoaded = false
+
+		project.UndoHistory.Reset()
 	}
 
 	for _, board := range project.Boards {
 		board.HandleDeletedTasks()
 	}
+
+	project.UndoHistory.Update()
 }
 
 func (project *Project) SendMessage(message string, data map[string]interface{}) {
@@ -670,7 +775,8 @@ func (project *Project) Shortcuts() {
 		clash.Enabled = false
 	}
 
-	if !project.ProjectSettingsOpen {
+	// Typing into a text field shouldn't set off shortcuts.
+	if !project.ProjectSettingsOpen && !imgui.CurrentIO().WantTextInput() {
 
 		if !project.TaskOpen {
 
@@ -694,53 +800,71 @@ func (project *Project) Shortcuts() {
 					project.CameraPan.X -= panSpeed
 				}
 
-				if keybindings.On(KBBoard1) {
-					if len(project.Boards) > 0 {
-						project.BoardIndex = 0
-					}
-				} else if keybindings.On(KBBoard2) {
-					if len(project.Boards) > 1 {
-						project.BoardIndex = 1
-					}
-				} else if keybindings.On(KBBoard2) {
-					if len(project.Boards) > 1 {
-						project.BoardIndex = 1
-					}
-				} else if keybindings.On(KBBoard3) {
-					if len(project.Boards) > 2 {
-						project.BoardIndex = 2
+				for bindingName, zoom := range zoomPresets {
+					if keybindings.On(bindingName) {
+						project.ZoomCentered(zoom)
 					}
-				} else if keybindings.On(KBBoard4) {
-					if len(project.Boards) > 3 {
-						project.BoardIndex = 3
-					}
-				} else if keybindings.On(KBBoard5) {
-					if len(project.Boards) > 4 {
-						project.BoardIndex = 4
-					}
-				} else if keybindings.On(KBBoard6) {
-					if len(project.Boards) > 5 {
-						project.BoardIndex = 5
-					}
-				} else if keybindings.On(KBBoard7) {
-					if len(project.Boards) > 6 {
-						project.BoardIndex = 6
-					}
-				} else if keybindings.On(KBBoard8) {
-					if len(project.Boards) > 7 {
-						project.BoardIndex = 7
+				}
+
+				boardShortcut := -1
+				for i, bindingName := range boardShortcuts {
+					if keybindings.On(bindingName) {
+						boardShortcut = i
+						break
 					}
-				} else if keybindings.On(KBBoard9) {
-					if len(project.Boards) > 8 {
-						project.BoardIndex = 8
+				}
+
+				if boardShortcut >= 0 {
+					project.SwitchBoard(boardShortcut)
+				} else if keybindings.On(KBNextBoard) {
+					project.SwitchBoardRelative(1)
+				} else if keybindings.On(KBPreviousBoard) {
+					project.SwitchBoardRelative(-1)
+				} else if keybindings.On(KBBoardHistoryBack) {
+					project.NavigateBoardHistory(-1)
+				} else if keybindings.On(KBBoardHistoryForward) {
+					project.NavigateBoardHistory(1)
+				} else if keybindings.On(KBQuickSwitchBoard) {
+					project.OpenBoardPicker()
+				} else if direction, ok := arrowKeyDirection(keybindings); ok {
+					if keybindings.On(KBSlideTask) {
+						project.CurrentBoard().SlideSelectedTasks(direction)
+					} else {
+						project.CurrentBoard().SelectTaskInDirection(direction)
 					}
-				} else if keybindings.On(KBBoard10) {
-					if len(project.Boards) > 9 {
-						project.BoardIndex = 9
+				} else if keybindings.On(KBSelectTopTaskInStack) {
+					project.CurrentBoard().CycleStack(1)
+				} else if keybindings.On(KBSelectBottomTaskInStack) {
+					project.CurrentBoard().CycleStack(-1)
+				} else if keybindings.On(KBFindPreviousTask) {
+					project.Search.Show()
+					project.Search.Find(-1)
+				} else if keybindings.On(KBFindNextTask) {
+					if project.Search.Open {
+						project.Search.Find(1)
+					} else {
+						project.Search.Show()
 					}
+				} else if keybindings.On(KBZoomIn) {
+					project.ZoomCentered(project.Zoom * 1.25)
+				} else if keybindings.On(KBZoomOut) {
+					project.ZoomCentered(project.Zoom / 1.25)
+				} else if keybindings.On(KBZoomToFitAll) {
+					project.ZoomToFit(project.CurrentBoard().Tasks)
+				} else if keybindings.On(KBZoomToFitSelection) {
+					project.ZoomToFit(project.CurrentBoard().SelectedTasks(false))
 				} else if keybindings.On(KBCenterView) {
 					project.CameraPan.X = 0
 					project.CameraPan.Y = 0
+				} else if keybindings.On(KBStopAllSounds) {
+					project.StopAllSounds()
+				} else if keybindings.On(KBToggleTasks) {
+					project.CurrentBoard().ToggleSelectedTasks()
+				} else if keybindings.On(KBConnectTasks) {
+					project.CurrentBoard().ConnectSelectedTasks()
+				} else if keybindings.On(KBToggleMinimap) {
+					programSettings.ShowMinimap = !programSettings.ShowMinimap
+					programSettings.Save()
 				} else if keybindings.On(KBSelectAllTasks) {
 
 					for _, task := range project.CurrentBoard().Tasks {
@@ -759,9 +883,9 @@ func (project *Project) Shortcuts() {
 					task := project.CurrentBoard().CreateNewTask()
 					task.ReceiveMessage(MessageDoubleClick, nil)
 				} else if keybindings.On(KBRedo) {
-          // NOTE(justasd): :Undo
+					project.UndoHistory.Redo()
 				} else if keybindings.On(KBUndo) {
-          // NOTE(justasd): :Undo
+					project.UndoHistory.Undo()
 				} else if keybindings.On(KBDeleteTasks) {
 					project.CurrentBoard().DeleteSelectedTasks()
 				} else if keybindings.On(KBFocusOnTasks) {
@@ -782,10 +906,7 @@ func (project *Project) Shortcuts() {
 						project.Save(false)
 					}
 				} else if keybindings.On(KBLoad) {
-					if project.Modified {
-					} else {
-						project.ExecuteDestructiveAction(ActionLoadProject, "")
-					}
+					project.ExecuteDestructiveAction(ActionLoadProject, "")
 				} else if keybindings.On(KBDeselectTasks) {
 					project.SendMessage(MessageSelect, nil)
 				}
@@ -804,59 +925,93 @@ func (project *Project) GetEmptyBoard() *Board {
 }
 
 func (project *Project) AddBoard() {
-	project.Boards = append(project.Boards, NewBoard(project))
+	board := NewBoard(project)
+	project.Boards = append(project.Boards, board)
+	project.UndoHistory.CaptureBoard(board, -1, len(project.Boards)-1)
 }
 
+// RemoveBoard takes the Board out of the Project. The Board isn't destroyed, as the undo history
+// can put it (and its Tasks) back.
 func (project *Project) RemoveBoard(board *Board) {
-	for index, b := range project.Boards {
-		if b == board {
-			b.Destroy()
-			project.Boards = append(project.Boards[:index], project.Boards[index+1:]...)
-			project.Log("Deleted empty Board: %s", b.Name)
-			break
-		}
+	if index := board.Index(); index >= 0 {
+		project.detachBoard(board)
+		project.UndoHistory.CaptureBoard(board, index, -1)
+		project.Log("Deleted Board: %s", board.Name)
 	}
 }
 
-func (project *Project) FirstFreeID() int {
+// MoveBoard moves the Board to the given index in the Board list, keeping the current Board the same.
+// Tasks save the index of the Board they're on, so they move along with it.
+func (project *Project) MoveBoard(board *Board, index int) {
 
-	usedIDs := map[int]bool{}
+	from := board.Index()
 
-	tasks := project.GetAllTasks()
+	project.placeBoard(board, index)
 
-	for i := 0; i < firstFreeTaskID; i++ {
-		if len(tasks) > i {
-			usedIDs[tasks[i].ID] = true
-		}
+	project.UndoHistory.CaptureBoard(board, from, board.Index())
+	project.MarkModified()
+
+}
+
+// placeBoard puts the Board at the given index in the Board list, taking it out of where it was first if it was in
+// the list already, while keeping the current Board the same.
+func (project *Project) placeBoard(board *Board, index int) {
+
+	current := project.CurrentBoard()
+
+	project.detachBoard(board)
+	project.insertBoard(board, index)
+
+	if currentIndex := current.Index(); currentIndex >= 0 {
+		project.BoardIndex = currentIndex
 	}
 
-	// Reuse already spent, but nonexistent IDs (i.e. create a task that has ID 4, then
-	// delete that and create a new one; it should have an ID of 4 so that when VCS diff
-	// the project file, it just alters the relevant pieces of info to make the original
-	// Task #4 the new Task #4)
-	for i := 0; i < firstFreeTaskID; i++ {
-		exists := usedIDs[i]
-		if !exists {
-			return i
-		}
+}
+
+func (project *Project) insertBoard(board *Board, index int) {
+
+	if index < 0 || index > len(project.Boards) {
+		index = len(project.Boards)
 	}
 
-	// If no spent but unused IDs exist, then we can just use a new one and move on.
-	id := firstFreeTaskID
+	boards := append([]*Board{}, project.Boards[:index]...)
+	boards = append(boards, board)
+	project.Boards = append(boards, project.Boards[index:]...)
 
-	firstFreeTaskID++
+}
 
-	return id
+func (project *Project) detachBoard(board *Board) {
+
+	for index, b := range project.Boards {
+		if b == board {
+			project.Boards = append(project.Boards[:index], project.Boards[index+1:]...)
+			break
+		}
+	}
+
+	if project.BoardIndex >= len(project.Boards) {
+		project.BoardIndex = len(project.Boards) - 1
+	}
 
 }
 
-func (project *Project) LockPositionToGrid(xy rl.Vector2) rl.Vector2 {
+// FirstFreeID returns the lowest ID none of the Project's Tasks have (see model.FirstFreeID()).
+func (project *Project) FirstFreeID() int {
 
-	return rl.Vector2{float32(math.Round(float64(xy.X/float32(project.GridSize)))) * float32(project.GridSize),
-		float32(math.Round(float64(xy.Y/float32(project.GridSize)))) * float32(project.GridSize)}
+	usedIDs := []int{}
+
+	for _, task := range project.GetAllTasks() {
+		usedIDs = append(usedIDs, task.ID)
+	}
+
+	return model.FirstFreeID(usedIDs)
 
 }
 
+func (project *Project) LockPositionToGrid(xy rl.Vector2) rl.Vector2 {
+	return rlVector(model.LockToGrid(modelVector(xy), project.GridSize))
+}
+
 func (project *Project) ReloadThemes() {
 
 	loadThemes()
@@ -887,14 +1042,17 @@ func (project *Project) Destroy() {
 		board.Destroy()
 	}
 
-	for _, res := range project.Resources {
-		res.Destroy()
-	}
+	project.closeResources()
+
+	project.Minimap.Destroy()
 
 }
 
 func (project *Project) RetrieveResource(resourcePath string) *Resource {
 
+	project.resourceMutex.Lock()
+	defer project.resourceMutex.Unlock()
+
 	existingResource, exists := project.Resources[resourcePath]
 
 	if exists {
@@ -905,149 +1063,99 @@ func (project *Project) RetrieveResource(resourcePath string) *Resource {
 }
 
 // LoadResource returns the resource loaded from the filepath and a boolean indicating if it was just loaded (true), or
-// loaded previously and retrieved (false).
+// loaded previously and retrieved (false). Resources are loaded in the background, so a newly loaded Resource is
+// still loading (its State is RESOURCE_STATE_LOADING) when it's returned; Tasks using it are told to load it again
+// once it's ready.
 func (project *Project) LoadResource(resourcePath string) (*Resource, bool) {
 
-	downloadedFile := false
-	newlyLoaded := false
-
-	var loadedResource *Resource
+	if resourcePath == "" {
+		return nil, false
+	}
 
+	project.resourceMutex.Lock()
 	existingResource, exists := project.Resources[resourcePath]
+	project.resourceMutex.Unlock()
 
 	if exists {
 
-		loadedResource = existingResource
-
-		// We check to see if the mod time isn't the same; if so, we destroy the old one and load it again
+		switch existingResource.State {
 
-		if file, err := os.Open(loadedResource.LocalFilepath); !loadedResource.Temporary && err == nil {
-
-			if stats, err := file.Stat(); err == nil {
-				// We have to check if the size is greater than 0 because it's possible we're seeing the file before it's been written fully to disk;
-				if stats.Size() > 0 && stats.ModTime().After(loadedResource.ModTime) {
-					loadedResource.Destroy()
-					delete(project.Resources, resourcePath)
-					loadedResource, newlyLoaded = project.LoadResource(resourcePath) // Force reloading if the file is outdated
-				}
-			}
+		case RESOURCE_STATE_LOADING:
+			return existingResource, false
 
-			file.Close()
+		case RESOURCE_STATE_FAILED:
+			// It could've been fixed since (e.g. the file's been put back or the network's back up), so we try again.
+			return project.loadResourceInBackground(resourcePath, nil), true
 
 		}
 
-	} else if resourcePath != "" {
+		// We check to see if the mod time isn't the same; if so, we load it again, destroying the old one once the new one's ready
 
-		localFilepath := resourcePath
+		if file, err := os.Open(existingResource.LocalFilepath); err == nil {
 
-		// Attempt downloading it if it's an HTTP file
-		if url, err := urlx.Parse(resourcePath); err == nil && url.Host != "" && url.Scheme != "" {
+			defer file.Close()
 
-			response, err := http.Get(url.String())
-
-			if err != nil {
-
-				project.Log("Could not open HTTP address: ", err.Error())
-
-			} else {
-
-				tempFile, err := ioutil.TempFile("", "masterplan_resource")
-				if err != nil {
-					project.Log("Could not open temporary file: ", err.Error())
-				} else {
-					io.Copy(tempFile, response.Body)
-					newlyLoaded = true
-					localFilepath = tempFile.Name()
-					downloadedFile = true
+			if stats, err := file.Stat(); err == nil {
+				// We have to check if the size is greater than 0 because it's possible we're seeing the file before it's been written fully to disk;
+				if stats.Size() > 0 && stats.ModTime().After(existingResource.ModTime) {
+					return project.loadResourceInBackground(resourcePath, existingResource), true // Force reloading if the file is outdated
 				}
-
-				response.Body.Close()
-
-				tempFile.Close()
-
 			}
 
 		}
 
-		fileType, err := mimetype.DetectFile(localFilepath)
+		return existingResource, false
 
-		if err != nil {
-			project.Log("Error identifying file type: ", err.Error())
-		} else {
-
-			newlyLoaded = true
+	} else if headless {
 
-			// We have to rename the resource according to what it is because raylib expects the extensions of files to be correct.
-			// png image files need to have .png as an extension, for example.
-			if downloadedFile && !fileType.Is(strings.ToLower(filepath.Ext(localFilepath))) {
-				newName := localFilepath + fileType.Extension()
-				os.Rename(localFilepath, newName)
-				localFilepath = newName
-			}
-
-			if strings.Contains(fileType.String(), "image") {
-
-				if strings.Contains(fileType.String(), "gif") {
-					file, err := os.Open(localFilepath)
-					if err != nil {
-						project.Log("Could not open GIF: ", err.Error())
-					} else {
+		// Without a window there's nothing to upload textures to or play sounds with, so local files are only registered
+		// to keep track of them (e.g. to serialize their paths relative to the project); remote ones aren't downloaded.
+		if FileExists(resourcePath) {
+			return project.RegisterResource(resourcePath, resourcePath, nil), true
+		}
 
-						defer file.Close()
+		return nil, false
 
-						gifFile, err := gif.DecodeAll(file)
+	}
 
-						if err != nil {
-							project.Log("Could not decode GIF: ", err.Error())
-						} else {
-							res := project.RegisterResource(resourcePath, localFilepath, gifFile)
-							res.Temporary = downloadedFile
-							loadedResource = res
-						}
+	return project.loadResourceInBackground(resourcePath, nil), true
 
-					}
-				} else { // Ordinary image
-					tex := rl.LoadTexture(localFilepath)
-					res := project.RegisterResource(resourcePath, localFilepath, tex)
-					res.Temporary = downloadedFile
-					loadedResource = res
-				}
+}
 
-			} else if strings.Contains(fileType.String(), "audio") {
-				res := project.RegisterResource(resourcePath, localFilepath, nil)
-				res.Temporary = downloadedFile
-				loadedResource = res
-			} else {
-				project.Log("Unable to load resource [%s].", resourcePath)
-			}
+func (project *Project) WorldToGrid(worldX, worldY float32) (int, int) {
+	cell := model.CellAt(worldX, worldY, project.GridSize)
+	return cell.X, cell.Y
+}
 
-		}
+// ExecuteDestructiveAction executes the action, first asking the user whether to save if it would throw away unsaved changes.
+func (project *Project) ExecuteDestructiveAction(action string, argument string) {
 
+	if project.Modified && (action == ActionNewProject || action == ActionLoadProject || action == ActionOpenBundle || action == ActionQuit) {
+		project.PendingAction = action
+		project.PendingActionArgument = argument
+		project.OpenUnsavedChangesPrompt = true
+		return
 	}
 
-	return loadedResource, newlyLoaded
-
-}
+	project.executeAction(action, argument)
 
-func (project *Project) WorldToGrid(worldX, worldY float32) (int, int) {
-	return int(worldX / float32(project.GridSize)), int(worldY / float32(project.GridSize))
 }
 
-func (project *Project) ExecuteDestructiveAction(action string, argument string) {
+func (project *Project) executeAction(action string, argument string) {
 
 	switch action {
 	case ActionNewProject:
 		project.Destroy()
-		currentProject = NewProject()
+		currentProject = NewProject(project.Viewport, project.Input)
 		currentProject.Log("New project created.")
 	case ActionLoadProject:
 
 		var loadProject *Project
 
 		if argument == "" {
-			loadProject = LoadProjectFrom()
+			loadProject = LoadProjectFrom(project.Viewport, project.Input)
 		} else {
-			loadProject = LoadProject(argument)
+			loadProject = LoadProject(argument, project.Viewport, project.Input)
 		}
 
 		// Unsuccessful loads will not destroy the current project
@@ -1056,9 +1164,32 @@ func (project *Project) ExecuteDestructiveAction(action string, argument string)
 			currentProject = loadProject
 		}
 
+	case ActionOpenBundle:
+
+		var loadProject *Project
+
+		if argument == "" {
+			loadProject = OpenBundleFrom(project.Viewport, project.Input)
+		} else {
+			loadProject = OpenBundle(argument, project.Viewport, project.Input)
+		}
+
+		if loadProject != nil {
+			currentProject.Destroy()
+			currentProject = loadProject
+		}
+
 	case ActionSaveAsProject:
 		project.FilePath = argument
 		project.Save(false)
+	case ActionRenameBoard:
+		board := project.CurrentBoard()
+		if argument != "" && argument != board.Name {
+			project.Log("Renamed Board: %s -> %s", board.Name, argument)
+			project.UndoHistory.CaptureBoardRename(board, board.Name, argument)
+			board.Name = argument
+			project.MarkModified()
+		}
 	case ActionQuit:
 		quit = true
 	}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"image/gif"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/goware/urlx"
+	"github.com/inkyblackness/imgui-go/v3"
+	"github.com/solarlune/masterplan/model"
+)
+
+type ResourceState int
+
+const (
+	RESOURCE_STATE_LOADING ResourceState = iota
+	RESOURCE_STATE_LOADED
+	RESOURCE_STATE_FAILED
+)
+
+// How many resources can be loading in the background at once.
+const resourceWorkerCount = 4
+
+// How long we spend uploading loaded resources to the GPU each frame, so opening a Project full of images doesn't
+// freeze the window once they've all finished loading.
+const resourceUploadBudget = 8 * time.Millisecond
+
+// Each worker takes a slot from here while it's loading.
+var resourceWorkers = make(chan struct{}, resourceWorkerCount)
+
+// resourceLoad is a Resource being loaded in the background. A worker downloads and decodes the file, and then the
+// load waits in the Project's queue for the main thread to upload it, as that's the only place textures can be made.
+type resourceLoad struct {
+	Resource *Resource
+	Replaces *Resource // An outdated copy of the Resource, to be destroyed once the new one's ready
+
+	LocalFilepath string
+	MimeData      *mimetype.MIME
+	ModTime       time.Time
+	Image         *rl.Image
+	Gif           *DecodedGif
+	Error         error
+}
+
+// loadResourceInBackground registers a Resource that's still loading under the resource path, and starts loading it.
+func (project *Project) loadResourceInBackground(resourcePath string, replaces *Resource) *Resource {
+
+	res := &Resource{
+		ResourcePath:  resourcePath,
+		LocalFilepath: resourcePath,
+		State:         RESOURCE_STATE_LOADING,
+	}
+
+	project.resourceMutex.Lock()
+	project.Resources[resourcePath] = res
+	project.resourceMutex.Unlock()
+
+	load := &resourceLoad{Resource: res, Replaces: replaces}
+
+	go func() {
+
+		resourceWorkers <- struct{}{}
+		load.decode()
+		<-resourceWorkers
+
+		project.resourceMutex.Lock()
+		defer project.resourceMutex.Unlock()
+
+		// The Project could've been closed while we were busy.
+		if project.resourcesClosed {
+			load.free()
+			return
+		}
+
+		project.loadedResources = append(project.loadedResources, load)
+
+	}()
+
+	return res
+
+}
+
+// decode does everything needed to load the Resource that doesn't have to happen on the main thread; that is,
+// downloading the file, working out what it is, and decoding it.
+func (load *resourceLoad) decode() {
+
+	resourcePath := load.Resource.ResourcePath
+	load.LocalFilepath = resourcePath
+
+	var downloadError error
+
+	// HTTP files are downloaded into (or, if they haven't changed, just used from) the resource cache
+	if url, err := urlx.Parse(resourcePath); err == nil && url.Host != "" && url.Scheme != "" {
+		if cachedFilepath, err := DefaultHTTPCache().Fetch(url.String()); err != nil {
+			downloadError = fmt.Errorf("could not download: %w", err)
+		} else {
+			defer DefaultHTTPCache().Release(url.String())
+			load.LocalFilepath = cachedFilepath
+		}
+	}
+
+	fileType, err := mimetype.DetectFile(load.LocalFilepath)
+
+	if err != nil {
+		if downloadError != nil {
+			err = downloadError
+		}
+		load.Error = err
+		return
+	}
+
+	load.MimeData = fileType
+
+	if stats, err := os.Stat(load.LocalFilepath); err == nil {
+		load.ModTime = stats.ModTime()
+	}
+
+	switch {
+
+	case strings.Contains(fileType.String(), "gif"):
+
+		file, err := os.Open(load.LocalFilepath)
+		if err != nil {
+			load.Error = err
+			return
+		}
+
+		defer file.Close()
+
+		data, err := gif.DecodeAll(file)
+		if err != nil {
+			load.Error = fmt.Errorf("could not decode GIF: %w", err)
+			return
+		}
+
+		load.Gif = DecodeGif(data)
+
+	case strings.Contains(fileType.String(), "image"):
+
+		// Loading an image into memory doesn't need the GPU, so raylib can do it off of the main thread.
+		img := rl.LoadImage(load.LocalFilepath)
+
+		if img == nil || img.Width == 0 || img.Height == 0 {
+			load.Error = fmt.Errorf("unsupported image format (%s)", fileType.Extension())
+			return
+		}
+
+		load.Image = img
+
+	case strings.Contains(fileType.String(), "audio"):
+		// Each sound Task streams its audio from the file itself, so there's nothing to load ahead of time.
+
+	default:
+		load.Error = fmt.Errorf("unsupported file type (%s)", fileType.String())
+
+	}
+
+}
+
+// free frees whatever was decoded without uploading it.
+func (load *resourceLoad) free() {
+
+	if load.Image != nil {
+		rl.UnloadImage(load.Image)
+		load.Image = nil
+	}
+
+	if load.Gif != nil {
+		load.Gif.Free()
+		load.Gif = nil
+	}
+
+}
+
+// finish uploads what was decoded, which makes the Resource ready to use.
+func (load *resourceLoad) finish() {
+
+	res := load.Resource
+
+	res.LocalFilepath = load.LocalFilepath
+	res.MimeData = load.MimeData
+	res.ModTime = load.ModTime
+
+	if load.Error != nil {
+		res.State = RESOURCE_STATE_FAILED
+		res.Error = load.Error
+		return
+	}
+
+	if load.Image != nil {
+		res.Data = rl.LoadTextureFromImage(load.Image)
+		rl.UnloadImage(load.Image)
+		load.Image = nil
+	} else if load.Gif != nil {
+		res.Data = load.Gif.Upload()
+		load.Gif = nil
+	}
+
+	res.State = RESOURCE_STATE_LOADED
+
+}
+
+// HandleLoadedResources uploads the Resources that have finished loading in the background, and has the Tasks using
+// them load them again now that they're ready. It's called each frame, but only spends so long uploading.
+func (project *Project) HandleLoadedResources() {
+
+	start := time.Now()
+
+	for time.Since(start) < resourceUploadBudget {
+
+		project.resourceMutex.Lock()
+
+		if len(project.loadedResources) == 0 {
+			project.resourceMutex.Unlock()
+			break
+		}
+
+		load := project.loadedResources[0]
+		project.loadedResources = project.loadedResources[1:]
+
+		project.resourceMutex.Unlock()
+
+		load.finish()
+
+		res := load.Resource
+
+		if res.State == RESOURCE_STATE_FAILED {
+			project.Log("Unable to load resource [%s]: %s", res.ResourcePath, res.Error.Error())
+		}
+
+		for _, task := range project.GetAllTasks() {
+
+			if !task.UsesMedia() || task.FilePath != res.ResourcePath {
+				continue
+			}
+
+			if res.State == RESOURCE_STATE_LOADED {
+
+				// Downloaded files are copied into the assets folder if the Project should keep its own copies;
+				// that has the Task load the copy instead.
+				if programSettings.CopyResourcesToAssets && model.IsRemotePath(task.FilePath) && project.AssetsPath() != "" {
+					if err := project.ImportTaskAsset(task); err != nil {
+						project.Log("Could not copy [%s] into the assets folder: %s", task.FilePath, err.Error())
+					}
+				}
+
+				task.LoadResource()
+
+			} else {
+				// Loading the Resource again would just try again, so we just let go of anything outdated instead.
+				task.Image = rl.Texture2D{}
+				task.Animation = nil
+			}
+
+		}
+
+		if load.Replaces != nil {
+			load.Replaces.Destroy()
+		}
+
+	}
+
+}
+
+// closeResources destroys all of the Project's Resources, including any that have finished loading but haven't been
+// uploaded yet; any that are still loading are freed as soon as they're done.
+func (project *Project) closeResources() {
+
+	project.resourceMutex.Lock()
+	defer project.resourceMutex.Unlock()
+
+	project.resourcesClosed = true
+
+	for _, load := range project.loadedResources {
+		load.free()
+		if load.Replaces != nil {
+			load.Replaces.Destroy()
+		}
+	}
+
+	project.loadedResources = nil
+
+	for _, res := range project.Resources {
+		res.Destroy()
+	}
+
+}
+
+// ResourceState returns how loading the Task's file is going, along with why it failed if it did. Tasks without a
+// file count as loaded, as there's nothing to wait on.
+func (task *Task) ResourceState() (ResourceState, error) {
+
+	if task.UsesMedia() && task.FilePath != "" {
+		if res := task.Board.Project.RetrieveResource(task.FilePath); res != nil {
+			return res.State, res.Error
+		}
+	}
+
+	return RESOURCE_STATE_LOADED, nil
+
+}
+
+// drawResourceState draws a placeholder in place of a Task whose file is still loading, with a bar sweeping back and
+// forth along the bottom, or an error badge if the file couldn't be loaded.
+func (task *Task) drawResourceState(state ResourceState) {
+
+	rect := task.Rect
+
+	rl.DrawRectangleRec(rect, getThemeColor(GUI_INSIDE))
+
+	label := ""
+
+	if state == RESOURCE_STATE_LOADING {
+
+		sweep := float32(math.Abs(math.Mod(float64(rl.GetTime()), 2) - 1))
+		bar := rl.Rectangle{0, rect.Y + rect.Height - 4, rect.Width / 4, 4}
+		bar.X = rect.X + (rect.Width-bar.Width)*sweep
+
+		rl.DrawRectangleRec(bar, getThemeColor(GUI_OUTLINE_HIGHLIGHTED))
+		rl.DrawRectangleLinesEx(rect, 1, getThemeColor(GUI_OUTLINE))
+
+		label = "Loading " + filepath.Base(task.FilePath)
+
+	} else {
+
+		rl.DrawRectangleLinesEx(rect, 1, rl.Red)
+
+		badge := rl.Rectangle{rect.X + rect.Width - 16, rect.Y, 16, 16}
+		rl.DrawRectangleRec(badge, rl.Red)
+		mark := rl.MeasureTextEx(not_shit_font, "!", 16, spacing)
+		rl.DrawTextEx(not_shit_font, "!", rl.Vector2{badge.X + (badge.Width-mark.X)/2, badge.Y}, 16, spacing, rl.White)
+
+		label = "Could not load " + filepath.Base(task.FilePath)
+
+	}
+
+	// The label's left off if the Task's too small to fit it, rather than spilling out over its neighbors.
+	if size := rl.MeasureTextEx(not_shit_font, label, 16, spacing); size.X+24 <= rect.Width && size.Y+8 <= rect.Height {
+		rl.DrawTextEx(not_shit_font, label, rl.Vector2{rect.X + 4, rect.Y + 2}, 16, spacing, getThemeColor(GUI_FONT_COLOR))
+	}
+
+}
+
+// drawResourceStateEditor shows whether the Task's file is still loading or couldn't be loaded in its editor window,
+// returning if the file's ready to use.
+func (task *Task) drawResourceStateEditor() bool {
+
+	state, err := task.ResourceState()
+
+	switch state {
+	case RESOURCE_STATE_LOADING:
+		imgui.Text("Loading...")
+	case RESOURCE_STATE_FAILED:
+		imgui.Text("Could not load the file: " + err.Error())
+	}
+
+	return state == RESOURCE_STATE_LOADED
+
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/adrg/xdg"
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/flac"
 	"github.com/faiface/beep/mp3"
@@ -12,8 +15,23 @@ import (
 	"github.com/faiface/beep/wav"
 	"github.com/gabriel-vasile/mimetype"
 	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/solarlune/masterplan/httpcache"
 )
 
+// Where downloaded resources are kept, under the user's cache directory.
+const HTTP_CACHE_PATH = "MasterPlan/resources"
+
+// The cache of downloaded resources; it's shared, so resources being downloaded at the same time don't evict each
+// other (see DefaultHTTPCache()).
+var httpCache = httpcache.New(filepath.Join(xdg.CacheHome, HTTP_CACHE_PATH), 0)
+
+// DefaultHTTPCache returns the cache of downloaded resources in the user's cache directory, limited to the size in
+// the program settings.
+func DefaultHTTPCache() *httpcache.Cache {
+	httpCache.SetMaxSize(int64(programSettings.HTTPCacheMaxMB) * 1024 * 1024)
+	return httpCache
+}
+
 type Resource struct {
 	ModTime time.Time
 	// Path facing the object requesting the resouce (e.g. "~/home/pictures/test.png" or "https://solarlune.com/media/bartender.png")
@@ -25,12 +43,13 @@ type Resource struct {
 	// Pointer to the data the resource stands for (e.g. a rl.Texture2D for an image)
 	Data interface{}
 
-	// Whether or not the resource is located in the temporary directory
-	// (e.g. was downloaded by MasterPlan, and so should be deleted after usage).
-	Temporary bool
-
 	// MIME data for the Resource.
 	MimeData *mimetype.MIME
+
+	// Whether the Resource is still being loaded in the background, is ready, or couldn't be loaded (in which case,
+	// Error says why).
+	State ResourceState
+	Error error
 }
 
 func (project *Project) RegisterResource(resourcePath, localFilepath string, data interface{}) *Resource {
@@ -51,9 +70,13 @@ func (project *Project) RegisterResource(resourcePath, localFilepath string, dat
 		Data:          data,
 		MimeData:      mime,
 		ModTime:       modTime,
+		State:         RESOURCE_STATE_LOADED,
 	}
 
+	project.resourceMutex.Lock()
 	project.Resources[resourcePath] = res
+	project.resourceMutex.Unlock()
+
 	return res
 }
 
@@ -66,8 +89,17 @@ func (res *Resource) Texture() rl.Texture2D {
 	return res.Data.(rl.Texture2D)
 }
 
+func (res *Resource) IsGif() bool {
+	_, isGif := res.Data.(*GifAnimation)
+	return isGif
+}
+
+func (res *Resource) Gif() *GifAnimation {
+	return res.Data.(*GifAnimation)
+}
+
 func (res *Resource) IsAudio() bool {
-	return strings.Contains(res.MimeData.String(), "audio")
+	return res.MimeData != nil && strings.Contains(res.MimeData.String(), "audio")
 }
 
 // Audio is special in that there is no resource to be shared between Tasks like with Images, as each Task
@@ -77,38 +109,53 @@ func (res *Resource) IsAudio() bool {
 // going to be deleted).
 func (res *Resource) Audio() (beep.StreamSeekCloser, beep.Format, error) {
 
+	stream, format, err := res.decodeAudio()
+
+	if err != nil {
+		currentProject.Log("Could not load audio file: %s", err.Error())
+	}
+
+	return stream, format, err
+
+}
+
+// decodeAudio opens a new stream of the audio file, like Audio(), but leaves logging any error to the caller, so it
+// can be used off of the main thread.
+func (res *Resource) decodeAudio() (beep.StreamSeekCloser, beep.Format, error) {
+
 	var stream beep.StreamSeekCloser
 	var format beep.Format
-	var err error
-
-	if res.IsAudio() {
-
-		file, err := os.Open(res.LocalFilepath)
-		if err != nil {
-			currentProject.Log("Could not open audio file: %s", err.Error())
-		} else {
-
-			switch ext := res.MimeData.Extension(); ext {
-			case ".wav":
-				stream, format, err = wav.Decode(file)
-			case ".flac":
-				stream, format, err = flac.Decode(file)
-			case ".ogg":
-			case ".oga":
-				stream, format, err = vorbis.Decode(file)
-			case ".mp3":
-				stream, format, err = mp3.Decode(file)
-			}
-
-			if err != nil {
-				currentProject.Log("Error decoding audio file: %s", err.Error())
-			}
 
-		}
+	if !res.IsAudio() {
+		return stream, format, nil
+	}
 
+	file, err := os.Open(res.LocalFilepath)
+	if err != nil {
+		return stream, format, err
 	}
 
-	return stream, format, err
+	switch ext := res.MimeData.Extension(); ext {
+	case ".wav":
+		stream, format, err = wav.Decode(file)
+	case ".flac":
+		stream, format, err = flac.Decode(file)
+	case ".ogg", ".oga":
+		stream, format, err = vorbis.Decode(file)
+	case ".mp3":
+		stream, format, err = mp3.Decode(file)
+	}
+
+	if err != nil {
+		file.Close()
+		return nil, format, fmt.Errorf("could not decode: %w", err)
+	}
+
+	if stream == nil {
+		file.Close()
+	}
+
+	return stream, format, nil
 
 }
 
@@ -116,12 +163,10 @@ func (res *Resource) Destroy() {
 
 	if res.IsTexture() {
 		rl.UnloadTexture(res.Texture())
+	} else if res.IsGif() {
+		res.Gif().Destroy()
 	}
-	// GIFs don't need to be disposed of directly here; the file handle was already Closed.
 	// Audio streams are closed by the Task, as each Sound Task has its own stream.
-
-	if res.Temporary {
-		os.Remove(res.LocalFilepath)
-	}
+	// Downloaded files stay in the resource cache for next time.
 
 }
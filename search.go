@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/inkyblackness/imgui-go/v3"
+)
+
+// A SearchResult is a Task or, if Task is nil, a Board whose name matched the search.
+type SearchResult struct {
+	Board *Board
+	Task  *Task
+}
+
+// Search holds the state of the Project's search bar.
+type Search struct {
+	Project       *Project
+	Open          bool
+	Text          string
+	Regex         bool
+	CaseSensitive bool
+	// Index is the result that was last gone to, or -1 if there isn't one.
+	Index   int
+	Results []SearchResult
+	Error   string
+
+	focusInput bool
+	searched   bool
+	lastKey    searchKey // What the Results were found with
+}
+
+// searchKey is everything a search's results depend on, so they only need to be found again when it changes.
+type searchKey struct {
+	Text          string
+	Regex         bool
+	CaseSensitive bool
+	ModifiedCount int
+}
+
+func (search *Search) key() searchKey {
+	return searchKey{search.Text, search.Regex, search.CaseSensitive, search.Project.ModifiedCount}
+}
+
+func NewSearch(project *Project) *Search {
+	return &Search{Project: project, Index: -1}
+}
+
+// Show opens the search bar, focusing its text field.
+func (search *Search) Show() {
+	search.Open = true
+	search.focusInput = true
+}
+
+// matcher returns a function that reports whether text matches the search.
+func (search *Search) matcher() (func(string) bool, error) {
+
+	pattern := search.Text
+	if !search.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+
+	if !search.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	expression, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return expression.MatchString, nil
+
+}
+
+// Update finds the results for the search again, as the Tasks could have changed since it was last done; the
+// result last gone to stays current if it still matches. Results are ordered by Board, and then top to bottom and
+// left to right on their Board.
+func (search *Search) Update() {
+
+	current := search.Current()
+
+	search.searched = true
+	search.lastKey = search.key()
+
+	search.Results = []SearchResult{}
+	search.Index = -1
+	search.Error = ""
+
+	if search.Text == "" {
+		return
+	}
+
+	matches, err := search.matcher()
+	if err != nil {
+		search.Error = err.Error()
+		return
+	}
+
+	for _, board := range search.Project.Boards {
+
+		if matches(board.Name) {
+			search.Results = append(search.Results, SearchResult{Board: board})
+		}
+
+		tasks := []*Task{}
+
+		for _, task := range board.Tasks {
+			if matches(task.Description) || (task.FilePath != "" && matches(filepath.Base(task.FilePath))) {
+				tasks = append(tasks, task)
+			}
+		}
+
+		sort.SliceStable(tasks, func(i, j int) bool {
+			if tasks[i].Position.Y == tasks[j].Position.Y {
+				return tasks[i].Position.X < tasks[j].Position.X
+			}
+			return tasks[i].Position.Y < tasks[j].Position.Y
+		})
+
+		for _, task := range tasks {
+			search.Results = append(search.Results, SearchResult{Board: board, Task: task})
+		}
+
+	}
+
+	if current != nil {
+		for i, result := range search.Results {
+			if result == *current {
+				search.Index = i
+				break
+			}
+		}
+	}
+
+}
+
+// Refresh finds the results for the search again if the search or the Project has changed since they were last found.
+func (search *Search) Refresh() {
+	if !search.searched || search.key() != search.lastKey {
+		search.Update()
+	}
+}
+
+// Find goes to the next result (or the previous one, for negative directions), wrapping around, and switches to its
+// Board, selecting and centering the view on the Task.
+func (search *Search) Find(direction int) {
+
+	search.Update()
+	index := search.Index
+
+	if len(search.Results) == 0 {
+		if search.Text != "" && search.Error == "" {
+			search.Project.Log("No matches found for \"%s\".", search.Text)
+		}
+		return
+	}
+
+	if index < 0 && direction < 0 {
+		index = 0
+	}
+
+	count := len(search.Results)
+	search.Index = ((index+direction)%count + count) % count
+
+	result := search.Results[search.Index]
+	project := search.Project
+
+	project.SwitchBoard(result.Board.Index())
+
+	if result.Task != nil {
+		project.SendMessage(MessageSelect, map[string]interface{}{"task": result.Task})
+		result.Board.FocusViewOnSelectedTasks()
+	}
+
+}
+
+// Current returns the result that was last gone to.
+func (search *Search) Current() *SearchResult {
+	if search.Index >= 0 && search.Index < len(search.Results) {
+		return &search.Results[search.Index]
+	}
+	return nil
+}
+
+// Draw draws the search bar.
+func (search *Search) Draw() {
+
+	if !search.Open {
+		return
+	}
+
+	imgui.SetNextWindowSizeV(imgui.Vec2{X: 420, Y: 0}, imgui.ConditionFirstUseEver)
+
+	if imgui.BeginV("Find", &search.Open, imgui.WindowFlagsAlwaysAutoResize) {
+
+		if search.focusInput {
+			imgui.SetKeyboardFocusHere()
+			search.focusInput = false
+		}
+
+		if imgui.InputTextV("##Search", &search.Text, imgui.InputTextFlagsEnterReturnsTrue, nil) {
+			search.Find(1)
+			search.focusInput = true // Keep typing after pressing enter
+		} else {
+			search.Refresh()
+		}
+
+		imgui.SameLine()
+		if imgui.Button("Previous") {
+			search.Find(-1)
+		}
+
+		imgui.SameLine()
+		if imgui.Button("Next") {
+			search.Find(1)
+		}
+
+		imgui.Checkbox("Regex", &search.Regex)
+		imgui.SameLine()
+		imgui.Checkbox("Match Case", &search.CaseSensitive)
+
+		imgui.SameLine()
+
+		if search.Error != "" {
+			imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{X: 1, Y: 0.4, Z: 0.4, W: 1})
+			imgui.Text("Invalid regex")
+			imgui.PopStyleColor()
+		} else if search.Text != "" {
+			imgui.Text(fmt.Sprintf("%d / %d", search.Index+1, len(search.Results)))
+		}
+
+	}
+
+	imgui.End()
+
+}
+
+// DrawHighlights outlines the matching Tasks on the current Board, with the result last gone to outlined more
+// strongly. It draws in world space, so it should be called while the camera's active.
+func (search *Search) DrawHighlights() {
+
+	if !search.Open || search.Text == "" {
+		return
+	}
+
+	current := search.Current()
+	color := getThemeColor(GUI_OUTLINE_HIGHLIGHTED)
+
+	for _, result := range search.Results {
+
+		if result.Task == nil || result.Board != search.Project.CurrentBoard() {
+			continue
+		}
+
+		rect := result.Task.Rect
+		rect.X -= 4
+		rect.Y -= 4
+		rect.Width += 8
+		rect.Height += 8
+
+		if current != nil && current.Task == result.Task {
+			rl.DrawRectangleLinesEx(rect, 4, color)
+		} else {
+			rl.DrawRectangleLinesEx(rect, 2, rl.Fade(color, 0.5))
+		}
+
+	}
+
+}
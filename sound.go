@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+	"github.com/faiface/beep/speaker"
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/inkyblackness/imgui-go/v3"
+)
+
+// Everything's resampled to this rate before it goes to the speaker.
+const soundSampleRate = beep.SampleRate(44100)
+
+// How many bars a sound Task's waveform is drawn with.
+const waveformResolution = 256
+
+var speakerInitialized = false
+
+// initSpeaker gets the speaker ready the first time a sound is played, returning if it's usable.
+func initSpeaker() bool {
+
+	if !speakerInitialized {
+
+		if err := speaker.Init(soundSampleRate, soundSampleRate.N(time.Second/10)); err != nil {
+			currentProject.Log("Could not initialize audio: %s", err.Error())
+			return false
+		}
+
+		speakerInitialized = true
+
+	}
+
+	return true
+
+}
+
+// soundVolume returns the global volume setting as the exponent effects.Volume works with, and whether the sound
+// should be silent.
+func soundVolume() (float64, bool) {
+	if programSettings.SoundVolume <= 0 {
+		return 0, true
+	}
+	return math.Log2(float64(programSettings.SoundVolume)), false
+}
+
+// SoundPlayer plays back a sound Task's audio file. The stream is only handed to the speaker while it's playing;
+// while paused, nothing is being mixed.
+type SoundPlayer struct {
+	Stream beep.StreamSeekCloser
+	Format beep.Format
+
+	playing bool
+	// Each time the sound starts playing it gets a new generation, so the streamer from the last time it was
+	// played knows to stop.
+	generation int
+
+	volume *effects.Volume
+
+	waveform      []float32
+	waveformError error // Why the waveform couldn't be worked out, until it's been logged
+	waveformMutex sync.Mutex
+}
+
+func NewSoundPlayer(res *Resource) (*SoundPlayer, error) {
+
+	stream, format, err := res.Audio()
+	if err != nil {
+		return nil, err
+	}
+
+	if stream == nil {
+		return nil, fmt.Errorf("unsupported audio format")
+	}
+
+	player := &SoundPlayer{Stream: stream, Format: format}
+
+	go func() {
+
+		waveform, err := buildWaveform(res)
+
+		player.waveformMutex.Lock()
+		player.waveform = waveform
+		player.waveformError = err
+		player.waveformMutex.Unlock()
+
+	}()
+
+	return player, nil
+
+}
+
+// buildWaveform reads through a separate stream of the audio to find the loudness of each part of it for drawing.
+// It's done in the background, as long files can take a while to decode, so it returns any error rather than
+// logging it.
+func buildWaveform(res *Resource) ([]float32, error) {
+
+	stream, _, err := res.decodeAudio()
+	if err != nil {
+		return nil, err
+	}
+
+	if stream == nil {
+		return nil, fmt.Errorf("unsupported audio format")
+	}
+
+	defer stream.Close()
+
+	length := stream.Len()
+	if length <= 0 {
+		return nil, nil
+	}
+
+	waveform := make([]float32, waveformResolution)
+	samples := make([][2]float64, 4096)
+	position := 0
+
+	for {
+
+		n, ok := stream.Stream(samples)
+
+		for _, sample := range samples[:n] {
+
+			bucket := position * waveformResolution / length
+			if bucket >= waveformResolution {
+				bucket = waveformResolution - 1
+			}
+
+			amplitude := float32(math.Max(math.Abs(sample[0]), math.Abs(sample[1])))
+			if amplitude > waveform[bucket] {
+				waveform[bucket] = amplitude
+			}
+
+			position++
+
+		}
+
+		if !ok || n == 0 {
+			break
+		}
+
+	}
+
+	return waveform, stream.Err()
+
+}
+
+// Waveform returns the loudness of each part of the sound from 0 to 1, or nil if it hasn't been worked out yet.
+func (player *SoundPlayer) Waveform() []float32 {
+	player.waveformMutex.Lock()
+	defer player.waveformMutex.Unlock()
+	return player.waveform
+}
+
+// WaveformError returns why the waveform couldn't be worked out, if it couldn't, the first time it's called after
+// that; after that, it returns nil.
+func (player *SoundPlayer) WaveformError() error {
+	player.waveformMutex.Lock()
+	defer player.waveformMutex.Unlock()
+	err := player.waveformError
+	player.waveformError = nil
+	return err
+}
+
+// soundStreamer streams a SoundPlayer's audio to the speaker for one go of playing it; it ends when the sound is
+// paused or played again, and rewinds the sound when it reaches the end.
+type soundStreamer struct {
+	player     *SoundPlayer
+	generation int
+}
+
+func (streamer *soundStreamer) Stream(samples [][2]float64) (int, bool) {
+
+	player := streamer.player
+
+	if !player.playing || player.generation != streamer.generation {
+		return 0, false
+	}
+
+	n, ok := player.Stream.Stream(samples)
+
+	if !ok || n < len(samples) {
+		player.playing = false
+		player.Stream.Seek(0)
+	}
+
+	return n, n > 0
+
+}
+
+func (streamer *soundStreamer) Err() error {
+	return streamer.player.Stream.Err()
+}
+
+// Play starts the sound playing from where it was left off.
+func (player *SoundPlayer) Play() {
+
+	if player.Playing() || !initSpeaker() {
+		return
+	}
+
+	speaker.Lock()
+	player.playing = true
+	player.generation++
+	streamer := &soundStreamer{player: player, generation: player.generation}
+	speaker.Unlock()
+
+	player.volume = &effects.Volume{
+		Streamer: beep.Resample(4, player.Format.SampleRate, soundSampleRate, streamer),
+		Base:     2,
+	}
+
+	player.UpdateVolume()
+
+	speaker.Play(player.volume)
+
+}
+
+// UpdateVolume sets the sound's volume to the global volume setting.
+func (player *SoundPlayer) UpdateVolume() {
+
+	if player.volume == nil {
+		return
+	}
+
+	player.lock()
+	player.volume.Volume, player.volume.Silent = soundVolume()
+	player.unlock()
+
+}
+
+// Pause stops the sound where it is, to be picked back up from there.
+func (player *SoundPlayer) Pause() {
+	player.lock()
+	player.playing = false
+	player.unlock()
+}
+
+// Stop stops the sound and rewinds it to the start.
+func (player *SoundPlayer) Stop() {
+	player.lock()
+	player.playing = false
+	player.Stream.Seek(0)
+	player.unlock()
+}
+
+func (player *SoundPlayer) Playing() bool {
+	player.lock()
+	defer player.unlock()
+	return player.playing
+}
+
+// Position returns how far into the sound playback is.
+func (player *SoundPlayer) Position() time.Duration {
+	player.lock()
+	defer player.unlock()
+	return player.Format.SampleRate.D(player.Stream.Position())
+}
+
+// Length returns how long the sound is.
+func (player *SoundPlayer) Length() time.Duration {
+	return player.Format.SampleRate.D(player.Stream.Len())
+}
+
+// Seek moves playback to the point in the sound given.
+func (player *SoundPlayer) Seek(position time.Duration) {
+
+	sample := player.Format.SampleRate.N(position)
+
+	if sample < 0 {
+		sample = 0
+	} else if sample >= player.Stream.Len() {
+		sample = player.Stream.Len() - 1
+	}
+
+	player.lock()
+	player.Stream.Seek(sample)
+	player.unlock()
+
+}
+
+func (player *SoundPlayer) Close() {
+	player.Stop()
+	player.Stream.Close()
+}
+
+// The stream's only touched by the speaker once it's been initialized, so there's no need to lock it before then.
+func (player *SoundPlayer) lock() {
+	if speakerInitialized {
+		speaker.Lock()
+	}
+}
+
+func (player *SoundPlayer) unlock() {
+	if speakerInitialized {
+		speaker.Unlock()
+	}
+}
+
+// StopAllSounds stops every sound Task in the Project, on every Board.
+func (project *Project) StopAllSounds() {
+	for _, task := range project.GetAllTasks() {
+		if task.Sound != nil {
+			task.Sound.Stop()
+		}
+	}
+}
+
+// UpdateSoundVolume applies the global volume setting to every sound Task's playback.
+func (project *Project) UpdateSoundVolume() {
+	for _, task := range project.GetAllTasks() {
+		if task.Sound != nil {
+			task.Sound.UpdateVolume()
+		}
+	}
+}
+
+// formatSoundTime formats the duration as minutes and seconds, like "1:05".
+func formatSoundTime(duration time.Duration) string {
+	seconds := int(duration.Seconds())
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// drawSound draws a sound Task's waveform (or just a bar until the waveform's ready), with how far playback has got
+// through it, and the file's name and playback time.
+func (task *Task) drawSound() {
+
+	rect := task.Rect
+
+	rl.DrawRectangleRec(rect, getThemeColor(GUI_INSIDE))
+
+	if task.Sound == nil {
+		rl.DrawRectangleLinesEx(rect, 1, getThemeColor(GUI_OUTLINE_DISABLED))
+		rl.DrawTextEx(not_shit_font, "Unable to play "+filepath.Base(task.FilePath), rl.Vector2{rect.X + 4, rect.Y + 4}, 16, spacing, getThemeColor(GUI_FONT_COLOR))
+		return
+	}
+
+	progress := float32(0)
+	if length := task.Sound.Length(); length > 0 {
+		progress = float32(task.Sound.Position()) / float32(length)
+	}
+
+	playedColor := getThemeColor(GUI_OUTLINE_HIGHLIGHTED)
+	unplayedColor := rl.Fade(getThemeColor(GUI_FONT_COLOR), 0.5)
+
+	if waveform := task.Sound.Waveform(); waveform != nil {
+
+		barWidth := rect.Width / float32(len(waveform))
+		middle := rect.Y + rect.Height/2
+
+		for i, amplitude := range waveform {
+
+			height := amplitude * (rect.Height - 8)
+			if height < 1 {
+				height = 1
+			}
+
+			color := unplayedColor
+			if float32(i)/float32(len(waveform)) < progress {
+				color = playedColor
+			}
+
+			rl.DrawRectangleRec(rl.Rectangle{rect.X + float32(i)*barWidth, middle - height/2, barWidth, height}, color)
+
+		}
+
+	} else {
+		bar := rl.Rectangle{rect.X, rect.Y + rect.Height - 4, rect.Width, 4}
+		rl.DrawRectangleRec(bar, unplayedColor)
+		bar.Width *= progress
+		rl.DrawRectangleRec(bar, playedColor)
+	}
+
+	// The playhead
+	x := rect.X + rect.Width*progress
+	rl.DrawLineEx(rl.Vector2{x, rect.Y}, rl.Vector2{x, rect.Y + rect.Height}, 2, playedColor)
+
+	status := "Paused"
+	if task.Sound.Playing() {
+		status = "Playing"
+	}
+
+	label := fmt.Sprintf("%s - %s / %s (%s)", filepath.Base(task.FilePath), formatSoundTime(task.Sound.Position()), formatSoundTime(task.Sound.Length()), status)
+	rl.DrawTextEx(not_shit_font, label, rl.Vector2{rect.X + 4, rect.Y + 2}, 16, spacing, getThemeColor(GUI_FONT_COLOR))
+
+	rl.DrawRectangleLinesEx(rect, 1, getThemeColor(GUI_OUTLINE))
+
+}
+
+// drawSoundEditor draws the playback controls in a sound Task's editor window.
+func (task *Task) drawSoundEditor() {
+
+	if task.Sound == nil {
+		imgui.Text("The file can't be played.")
+		return
+	}
+
+	if task.Sound.Playing() {
+		if imgui.Button("Pause") {
+			task.Sound.Pause()
+		}
+	} else if imgui.Button("Play") {
+		task.Sound.Play()
+	}
+
+	imgui.SameLine()
+
+	if imgui.Button("Stop") {
+		task.Sound.Stop()
+	}
+
+	position := float32(task.Sound.Position().Seconds())
+	length := float32(task.Sound.Length().Seconds())
+
+	if imgui.SliderFloatV("Position", &position, 0, length, formatSoundTime(task.Sound.Position()), 1) {
+		task.Sound.Seek(time.Duration(float64(position) * float64(time.Second)))
+	}
+
+}
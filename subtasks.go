@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// UpdateHierarchy works out which Tasks are subtasks of which, if the Tasks have moved since it was last done.
+// Tasks stacked on top of each other form an outline, where a Task indented to the right of the Task above it is
+// its subtask; a Task's parent is the nearest Task above it in the stack that's further to the left.
+func (board *Board) UpdateHierarchy() {
+
+	if !board.hierarchyChanged {
+		return
+	}
+
+	board.hierarchyChanged = false
+
+	sorted := append([]*Task{}, board.Tasks...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Position.Y == sorted[j].Position.Y {
+			return sorted[i].Position.X < sorted[j].Position.X
+		}
+		return sorted[i].Position.Y < sorted[j].Position.Y
+	})
+
+	for _, task := range sorted {
+		task.Parent = nil
+		task.Children = nil
+		task.OriginalIndentation = 0
+		task.stackTop = task
+	}
+
+	// The open outline levels for each stack, from the top level down, keyed by the stack's topmost Task
+	levels := map[*Task][]*Task{}
+
+	for _, task := range sorted {
+
+		if above := board.taskAbove(task); above != nil {
+			task.stackTop = above.stackTop
+		}
+
+		open := levels[task.stackTop]
+
+		for len(open) > 0 && open[len(open)-1].Position.X >= task.Position.X {
+			open = open[:len(open)-1]
+		}
+
+		if len(open) > 0 {
+			parent := open[len(open)-1]
+			task.Parent = parent
+			task.OriginalIndentation = parent.OriginalIndentation + 1
+			parent.Children = append(parent.Children, task)
+		}
+
+		levels[task.stackTop] = append(open, task)
+
+	}
+
+}
+
+// taskAbove returns the Task directly on top of the given one; that is, one whose bottom edge touches the Task's
+// top edge with the two overlapping horizontally. If there are several, the leftmost one is returned.
+func (board *Board) taskAbove(task *Task) *Task {
+
+	gs := float32(board.Project.GridSize)
+
+	var above *Task
+
+	for x := task.Position.X; x < task.Position.X+task.Rect.Width; x += gs {
+
+		for _, other := range board.GetTasksInPosition(x, task.Position.Y-gs/2) {
+
+			if other == task || other.Position.Y+other.Rect.Height != task.Position.Y {
+				continue
+			}
+
+			if above == nil || other.Position.X < above.Position.X {
+				above = other
+			}
+
+		}
+
+	}
+
+	return above
+
+}
+
+// Completion returns how complete the Task is, from 0 to 1, and whether it has a completion at all. Checkboxes and
+// progression Tasks do, as do Tasks with subtasks that have one; those roll up the average of their subtasks.
+func (task *Task) Completion() (float32, bool) {
+
+	total := float32(0)
+	count := 0
+
+	for _, child := range task.Children {
+		if completion, ok := child.Completion(); ok {
+			total += completion
+			count++
+		}
+	}
+
+	if count > 0 {
+		return total / float32(count), true
+	}
+
+	switch task.TaskType {
+
+	case TASK_TYPE_CHECKBOX:
+		if task.Checked {
+			return 1, true
+		}
+		return 0, true
+
+	case TASK_TYPE_PROGRESSION:
+		if task.ProgressionMax <= 0 {
+			return 0, true
+		}
+		return float32(math.Min(1, math.Max(0, float64(task.ProgressionCurrent)/float64(task.ProgressionMax)))), true
+
+	}
+
+	return 0, false
+
+}
+
+// HasSubtaskCompletion returns whether the Task's completion comes from its subtasks rather than the Task itself.
+func (task *Task) HasSubtaskCompletion() bool {
+	for _, child := range task.Children {
+		if _, ok := child.Completion(); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCompleted completes or uncompletes the Task, along with all of its subtasks.
+func (task *Task) SetCompleted(completed bool) {
+
+	switch task.TaskType {
+
+	case TASK_TYPE_CHECKBOX:
+		task.Checked = completed
+
+	case TASK_TYPE_PROGRESSION:
+		if completed {
+			task.ProgressionCurrent = task.ProgressionMax
+		} else {
+			task.ProgressionCurrent = 0
+		}
+
+	}
+
+	task.Board.Project.UndoHistory.Capture(task)
+
+	for _, child := range task.Children {
+		child.SetCompleted(completed)
+	}
+
+}
+
+// ToggleSelectedTasks completes the selected Tasks that aren't complete; if they all are, they're all uncompleted
+// instead. Selected animated images and sounds are played or paused.
+func (board *Board) ToggleSelectedTasks() {
+
+	board.UpdateHierarchy()
+
+	toggleable := []*Task{}
+	completed := true
+
+	for _, task := range board.SelectedTasks(false) {
+		if task.Sound != nil {
+			if task.Sound.Playing() {
+				task.Sound.Pause()
+			} else {
+				task.Sound.Play()
+			}
+		} else if task.Animation != nil {
+			task.AnimationPaused = !task.AnimationPaused
+			board.Project.UndoHistory.Capture(task)
+		} else if completion, ok := task.Completion(); ok {
+			toggleable = append(toggleable, task)
+			if completion < 1 {
+				completed = false
+			}
+		}
+	}
+
+	for _, task := range toggleable {
+		task.SetCompleted(!completed)
+	}
+
+}
+
+// completionPrefix returns the text shown before the Task's description for its completion, if there is any.
+func (task *Task) completionPrefix() string {
+
+	if task.HasSubtaskCompletion() {
+		completion, _ := task.Completion()
+		return fmt.Sprintf("%d%%", int(math.Round(float64(completion*100))))
+	}
+
+	if task.Is(TASK_TYPE_PROGRESSION) {
+		return fmt.Sprintf("%d / %d", task.ProgressionCurrent, task.ProgressionMax)
+	}
+
+	return ""
+
+}
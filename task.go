@@ -3,23 +3,31 @@ package main
 import (
   "fmt"
   "math"
-  "path/filepath"
   "strings"
   "time"
 
   //"github.com/goware/urlx"
-  //"github.com/ncruces/zenity"
-  "github.com/tidwall/gjson"
-  "github.com/tidwall/sjson"
+  "github.com/ncruces/zenity"
+  "github.com/inkyblackness/imgui-go/v3"
 
   rl "github.com/gen2brain/raylib-go/raylib"
+  "github.com/solarlune/masterplan/model"
 )
 
+// The size of a note's text when its TextSize isn't set.
+const defaultNoteTextSize = 128
+
 const (
-  TASK_TYPE_NOTE = "note"
-  TASK_TYPE_IMAGE = "image"
+  TASK_TYPE_NOTE = model.TASK_TYPE_NOTE
+  TASK_TYPE_IMAGE = model.TASK_TYPE_IMAGE
+  TASK_TYPE_CHECKBOX = model.TASK_TYPE_CHECKBOX
+  TASK_TYPE_PROGRESSION = model.TASK_TYPE_PROGRESSION
+  TASK_TYPE_SOUND = model.TASK_TYPE_SOUND
 )
 
+// TaskTypes lists every Task type in the order they're presented to the user.
+var TaskTypes = model.TaskTypes
+
 type URLButton struct {
   Pos  rl.Vector2
   Text string
@@ -42,9 +50,21 @@ type Task struct {
   CreationTime   time.Time
 
   TextSize float32
+  textLayout *MarkdownLayout
+
+  Checked            bool
+  ProgressionCurrent int
+  ProgressionMax     int
 
   Image                        rl.Texture2D
 
+  // Animated GIFs show their Animation's frames in Image as it plays.
+  Animation       *GifAnimation
+  AnimationPaused bool
+  animationTime   float32
+
+  Sound *SoundPlayer
+
   FilePath string
   PrevFilePath  string
   DisplaySize        rl.Vector2
@@ -55,12 +75,18 @@ type Task struct {
   ResizeRect         rl.Rectangle
   ImageSizeResetRect rl.Rectangle
 
-  OriginalIndentation int
-  PrefixText          string
+  OriginalIndentation int    // How many levels deep the Task is as a subtask
+  PrefixText          string // Shown before the description, e.g. the Task's completion
   ID                  int
   Visible             bool
 
-  GridPositions []Position
+  // The Task's place among subtasks, worked out by Board.UpdateHierarchy()
+  Parent   *Task
+  Children []*Task
+  stackTop *Task
+
+  // The Connections removed along with the Task when it was deleted, in case the deletion's undone
+  detachedConnections []*Connection
 
   SuccessfullyLoadedResourceOnce bool
 }
@@ -76,7 +102,6 @@ func NewTask(board *Board) *Task {
     Description: "",
     ID: board.Project.FirstFreeID(),
     FilePath: "",
-    GridPositions: []Position{},
     CreationTime: time.Now(),
   }
 
@@ -89,6 +114,8 @@ func NewTask(board *Board) *Task {
 func (task *Task) Clone() *Task {
   copyData := *task
   copyData.PrevFilePath = ""
+  copyData.detachedConnections = nil
+  copyData.Sound = nil // The clone gets its own stream when its resource is loaded
 
   copyData.ID = copyData.Board.Project.FirstFreeID()
 
@@ -97,58 +124,29 @@ func (task *Task) Clone() *Task {
   return &copyData
 }
 
-// Serialize returns the Task's changeable properties in the form of a complete JSON object in a string.
-func (task *Task) Serialize() string {
-
-  jsonData := "{}"
-
-  jsonData, _ = sjson.Set(jsonData, `BoardIndex`, task.Board.Index())
-  jsonData, _ = sjson.Set(jsonData, `Position\.X`, task.Position.X)
-  jsonData, _ = sjson.Set(jsonData, `Position\.Y`, task.Position.Y)
-
-  if task.Resizeable() {
-    jsonData, _ = sjson.Set(jsonData, `ImageDisplaySize\.X`, task.DisplaySize.X)
-    jsonData, _ = sjson.Set(jsonData, `ImageDisplaySize\.Y`, task.DisplaySize.Y)
+// Data returns the Task's changeable properties, as they're stored.
+func (task *Task) Data() model.TaskData {
+  return model.TaskData{
+    ID:                 task.ID,
+    BoardIndex:         task.Board.Index(),
+    Position:           modelVector(task.Position),
+    DisplaySize:        modelVector(task.DisplaySize),
+    Description:        task.Description,
+    FilePath:           task.FilePath,
+    Selected:           task.Selected,
+    TaskType:           task.TaskType,
+    CreationTime:       task.CreationTime,
+    TextSize:           task.TextSize,
+    AnimationPaused:    task.AnimationPaused,
+    Checked:            task.Checked,
+    ProgressionCurrent: task.ProgressionCurrent,
+    ProgressionMax:     task.ProgressionMax,
   }
+}
 
-  jsonData, _ = sjson.Set(jsonData, `Description`, task.Description)
-
-  if task.UsesMedia() && task.FilePath != "" {
-
-    resourcePath := task.FilePath
-
-    if resource := task.Board.Project.RetrieveResource(resourcePath); resource != nil && !resource.Temporary {
-
-      // Turn the file path absolute if it's not a remote path
-      relative, err := filepath.Rel(filepath.Dir(task.Board.Project.FilePath), resourcePath)
-
-      if err == nil {
-
-        jsonData, _ = sjson.Set(jsonData, `FilePath`, strings.Split(relative, string(filepath.Separator)))
-        resourcePath = ""
-
-      }
-
-    }
-
-    if resourcePath != "" {
-      jsonData, _ = sjson.Set(jsonData, `FilePath`, resourcePath)
-    }
-
-  }
-
-  jsonData, _ = sjson.Set(jsonData, `Selected`, task.Selected)
-
-  jsonData, _ = sjson.Set(jsonData, `TaskType\.CurrentChoice`, task.TaskType)
-
-  jsonData, _ = sjson.Set(jsonData, `CreationTime`, task.CreationTime.Format(`Jan 2 2006 15:04:05`))
-
-  if task.Is(TASK_TYPE_NOTE) {
-    jsonData, _ = sjson.Set(jsonData, `TextSize`, task.TextSize)
-  }
-
-  return jsonData
-
+// Serialize returns the Task's changeable properties in the form of a complete JSON object in a string.
+func (task *Task) Serialize() string {
+  return task.Data().Serialize(task.Board.Project.FilePath)
 }
 
 // Serializable returns if Tasks are able to be serialized properly. Only line endings aren't properly serializeable
@@ -163,73 +161,46 @@ func (task *Task) Serializable() bool {
 // the functions to work (as e.g. loading numbers from JSON gives float64s, but passing the map[string]interface{} directly from
 // deserialization to serialization contains values that may be other discrete number types).
 func (task *Task) Deserialize(jsonData string) {
+  task.applyData(model.ParseTaskData(jsonData, task.Board.Project.FilePath))
+}
 
-  // JSON encodes all numbers as 64-bit floats, so this saves us some visual ugliness.
-  getFloat := func(name string) float32 {
-    return float32(gjson.Get(jsonData, name).Float())
-  }
-
-  //	getInt := func(name string) int {
-  //		return int(gjson.Get(jsonData, name).Int())
-  //	}
-
-  getBool := func(name string) bool {
-    return gjson.Get(jsonData, name).Bool()
-  }
+// applyData sets the Task's changeable properties to the data given, and loads its file.
+func (task *Task) applyData(data model.TaskData) {
 
-  getString := func(name string) string {
-    return gjson.Get(jsonData, name).String()
+  // Connections refer to Tasks by ID, so it has to stay the same between saving and loading.
+  if data.ID >= 0 {
+    task.ID = data.ID
   }
 
-  //hasData := func(name string) bool {
-  //  return gjson.Get(jsonData, name).Exists()
-  //}
-
-  task.Position.X = getFloat(`Position\.X`)
-  task.Position.Y = getFloat(`Position\.Y`)
+  task.Position = rlVector(data.Position)
 
   task.Rect.X = task.Position.X
   task.Rect.Y = task.Position.Y
 
-  if gjson.Get(jsonData, `ImageDisplaySize\.X`).Exists() {
-    task.DisplaySize.X = getFloat(`ImageDisplaySize\.X`)
-    task.DisplaySize.Y = getFloat(`ImageDisplaySize\.Y`)
+  if data.Resizeable() {
+    task.DisplaySize = rlVector(data.DisplaySize)
   }
 
-  task.Description = getString(`Description`)
+  task.Description = data.Description
 
-  if f := gjson.Get(jsonData, `FilePath`); f.Exists() {
-
-    if f.IsArray() {
-      str := []string{}
-      for _, component := range f.Array() {
-        str = append(str, component.String())
-      }
+  // No FilePath means the Task doesn't have a file, so undoing back to before it had one takes it away again.
+  task.FilePath = data.FilePath
 
-      // We need to go from the project file as the "root", as otherwise it will be relative
-      // to the current working directory (which is not ideal).
-      str = append([]string{filepath.Dir(task.Board.Project.FilePath)}, str...)
-      joinedElements := strings.Join(str, string(filepath.Separator))
-      abs, _ := filepath.Abs(joinedElements)
-
-      task.FilePath = abs
-    } else {
-      task.FilePath = getString(`FilePath`)
-    }
+  task.Selected = data.Selected
+  task.TaskType = data.TaskType
 
+  if !data.CreationTime.IsZero() {
+    task.CreationTime = data.CreationTime
   }
 
-  task.Selected = getBool(`Selected`)
-  task.TaskType = getString(`TaskType\.CurrentChoice`)
-
-  creationTime, err := time.Parse(`Jan 2 2006 15:04:05`, getString(`CreationTime`))
-  if err == nil {
-    task.CreationTime = creationTime
+  if data.HasText() {
+    task.TextSize = data.TextSize
   }
 
-  if task.TaskType == TASK_TYPE_NOTE {
-    task.TextSize = getFloat(`TextSize`)
-  }
+  task.AnimationPaused = data.AnimationPaused
+  task.Checked = data.Checked
+  task.ProgressionCurrent = data.ProgressionCurrent
+  task.ProgressionMax = data.ProgressionMax
 
   // We do this to update the task after loading all of the information.
   task.LoadResource()
@@ -241,16 +212,14 @@ func (task *Task) Update() {
   task.MaxSize = rl.Vector2{0, 0}
 
   if task.Selected && task.Dragging && !task.Resizing {
-    delta := rl.Vector2Subtract(GetWorldMousePosition(), task.MouseDragStart)
+    delta := rl.Vector2Subtract(rlVector(task.Board.Project.Viewport.WorldMousePosition()), task.MouseDragStart)
     task.Position = rl.Vector2Add(task.TaskDragStart, delta)
     task.Rect.X = task.Position.X
     task.Rect.Y = task.Position.Y
   }
 
-  if task.Dragging && MouseReleased(rl.MouseLeftButton) {
-    //task.Board.SendMessage(MessageDropped, nil)
-    task.Dragging = false
-    //task.Board.ReorderTasks()
+  if task.Dragging && task.Board.Project.Input.MouseReleased(rl.MouseLeftButton) {
+    task.ReceiveMessage(MessageDropped, nil)
   }
 
   if !task.Dragging || task.Resizing {
@@ -265,16 +234,30 @@ func (task *Task) Update() {
 
   }
 
+  // The waveform's worked out in the background, so anything that went wrong with it is logged once it's done.
+  if task.Sound != nil {
+    if err := task.Sound.WaveformError(); err != nil {
+      task.Board.Project.Log("Could not read the waveform of [%s]: %s", task.FilePath, err.Error())
+    }
+  }
+
+  if task.Animation != nil && len(task.Animation.Frames) > 0 {
+
+    if !task.AnimationPaused && !animationsFrozen() {
+      task.animationTime = float32(math.Mod(float64(task.animationTime+task.Board.Project.GetFrameTime()), float64(task.Animation.Duration())))
+    }
+
+    task.Image = task.Animation.Frames[task.Animation.FrameAt(task.animationTime)]
+
+  }
+
   task.Rect.X += (task.Position.X - task.Rect.X) * 0.2
   task.Rect.Y += (task.Position.Y - task.Rect.Y) * 0.2
 
   task.Visible = true
 
-  scrW := float32(rl.GetScreenWidth()) / camera.Zoom
-  scrH := float32(rl.GetScreenHeight()) / camera.Zoom
-
   // Slight optimization
-  cameraRect := rl.Rectangle{camera.Target.X - (scrW / 2), camera.Target.Y - (scrH / 2), scrW, scrH}
+  cameraRect := rlRect(task.Board.Project.Viewport.VisibleRect())
 
   if task.Board.Project.FullyInitialized {
     if !rl.CheckCollisionRecs(task.Rect, cameraRect) {
@@ -303,11 +286,11 @@ func (task *Task) Update() {
 
     selectedTaskCount := len(task.Board.SelectedTasks(false))
 
-    if rl.CheckCollisionPointRec(GetWorldMousePosition(), task.ResizeRect) && MousePressed(rl.MouseLeftButton) && selectedTaskCount == 1 {
+    if rl.CheckCollisionPointRec(rlVector(task.Board.Project.Viewport.WorldMousePosition()), task.ResizeRect) && task.Board.Project.Input.MousePressed(rl.MouseLeftButton) && selectedTaskCount == 1 {
       task.Resizing = true
       task.Board.Project.ResizingImage = true
       task.Board.SendMessage(MessageDropped, nil)
-    } else if !MouseDown(rl.MouseLeftButton) || task.Open || task.Board.Project.ContextMenuOpen {
+    } else if !task.Board.Project.Input.MouseDown(rl.MouseLeftButton) || task.Open || task.Board.Project.ContextMenuOpen {
       if task.Resizing {
         task.Resizing = false
         task.Board.Project.ResizingImage = false
@@ -317,20 +300,20 @@ func (task *Task) Update() {
 
     if task.Resizing {
 
-      endPoint := GetWorldMousePosition()
+      endPoint := rlVector(task.Board.Project.Viewport.WorldMousePosition())
 
       task.DisplaySize.X = endPoint.X - task.Rect.X
       task.DisplaySize.Y = endPoint.Y - task.Rect.Y
 
-      if task.Is(TASK_TYPE_IMAGE, TASK_TYPE_NOTE) {
+      if task.Is(TASK_TYPE_IMAGE) || task.HasText() {
 
-        if !programSettings.Keybindings.On(KBUnlockImageASR) {
+        if task.Is(TASK_TYPE_IMAGE) && task.Image.Width > 0 && !task.Board.Project.Input.BindingOn(KBUnlockImageASR) {
           asr := float32(task.Image.Height) / float32(task.Image.Width)
           task.DisplaySize.Y = task.DisplaySize.X * asr
 
         }
 
-        if !programSettings.Keybindings.On(KBUnlockImageGrid) {
+        if !task.Board.Project.Input.BindingOn(KBUnlockImageGrid) {
           task.DisplaySize = task.Board.Project.LockPositionToGrid(task.DisplaySize)
         }
 
@@ -364,7 +347,7 @@ func (task *Task) Update() {
       task.ImageSizeResetRect.X = task.Rect.X
       task.ImageSizeResetRect.Y = task.Rect.Y
 
-      if selectedTaskCount == 1 && rl.CheckCollisionPointRec(GetWorldMousePosition(), task.ImageSizeResetRect) && MousePressed(rl.MouseLeftButton) {
+      if selectedTaskCount == 1 && rl.CheckCollisionPointRec(rlVector(task.Board.Project.Viewport.WorldMousePosition()), task.ImageSizeResetRect) && task.Board.Project.Input.MousePressed(rl.MouseLeftButton) {
         task.DisplaySize.X = float32(task.Image.Width)
         task.DisplaySize.Y = float32(task.Image.Height)
       }
@@ -378,48 +361,29 @@ func (task *Task) Draw() {
     return
   }
 
-  name := task.Description
-
-  //extendedText := false
-
-  taskType := task.TaskType
-
-  switch taskType {
-
-  case TASK_TYPE_IMAGE:
-    _, filename := filepath.Split(task.FilePath)
-    name = filename
-  }
-
-  invalidImage := task.Image.ID == 0 
-  if !invalidImage && task.Is(TASK_TYPE_IMAGE) {
-    name = ""
-  }
-
   taskDisplaySize := task.DisplaySize
 
-  // NOTE(justasd): :Text
-  /*
-  if task.Is(TASK_TYPE_NOTE) {
+  if task.HasText() {
+
+    task.PrefixText = task.completionPrefix()
 
-    taskDisplaySize = rl.MeasureTextEx(font, name, float32(programSettings.FontSize), spacing)
+    // Notes are as tall as their text needs; they're as wide as it is, too, unless they've been resized to
+    // a width to wrap it at.
+    layout := task.NoteLayout()
 
-    if taskDisplaySize.X > 0 {
-      taskDisplaySize.X += 4
+    if task.DisplaySize.X < task.MinSize.X {
+      taskDisplaySize.X = layout.Size.X + 4 + task.textIndent()
     }
 
-    if task.Board.Project.ShowIcons.Checked && (!task.Is(TASK_TYPE_IMAGE) || invalidImage) {
-      taskDisplaySize.X += 16
-      if extendedText {
-        taskDisplaySize.X += 16
-      }
+    if layout.Size.Y+4 > taskDisplaySize.Y {
+      taskDisplaySize.Y = layout.Size.Y + 4
     }
 
-    taskDisplaySize.Y, _ = TextHeight(name, false) // Custom spacing to better deal with custom fonts
-    taskDisplaySize.X = float32((math.Ceil(float64((taskDisplaySize.X + 4) / float32(task.Board.Project.GridSize))))) * float32(task.Board.Project.GridSize)
-    taskDisplaySize.Y = float32((math.Ceil(float64((taskDisplaySize.Y) / float32(task.Board.Project.GridSize))))) * float32(task.Board.Project.GridSize)
+    gridSize := float64(task.Board.Project.GridSize)
+    taskDisplaySize.X = float32(math.Ceil(float64(taskDisplaySize.X)/gridSize) * gridSize)
+    taskDisplaySize.Y = float32(math.Ceil(float64(taskDisplaySize.Y)/gridSize) * gridSize)
+
   }
-  */
 
   if taskDisplaySize.X < task.MinSize.X {
     taskDisplaySize.X = task.MinSize.X
@@ -470,7 +434,11 @@ func (task *Task) Draw() {
 
   //alpha := uint8(255)
 
-  if task.Is(TASK_TYPE_IMAGE) {
+  if resourceState, _ := task.ResourceState(); resourceState != RESOURCE_STATE_LOADED {
+
+    task.drawResourceState(resourceState)
+
+  } else if task.Is(TASK_TYPE_IMAGE) {
 
     if task.Image.ID != 0 {
 
@@ -482,6 +450,9 @@ func (task *Task) Draw() {
       color := rl.White
       rl.DrawTexturePro(task.Image, src, dst, rl.Vector2{}, 0, color)
     }
+
+  } else if task.Is(TASK_TYPE_SOUND) {
+    task.drawSound()
   }
 
   if task.Resizeable() && task.Selected && (!task.Is(TASK_TYPE_IMAGE) || task.Image.ID > 0) {
@@ -503,36 +474,98 @@ func (task *Task) Draw() {
 
   }
 
-  if !task.Is(TASK_TYPE_IMAGE) {
+  if task.HasText() {
 
-    textPos := rl.Vector2{task.Rect.X + 2, task.Rect.Y + 2}
+    textSize := task.textSize()
+    textPos := rl.Vector2{task.Rect.X + 2, task.Rect.Y} // Text is a bit low, so it's not offset down as much
 
-    // NOTE(justasd): :Text
-    //DrawText(textPos, name)
+    completion, hasCompletion := task.Completion()
 
-    {
-      text := name
-      pos := textPos
+    if task.Is(TASK_TYPE_CHECKBOX) {
 
-      pos.Y -= 2 // Text is a bit low
+      box := rl.Rectangle{textPos.X + textSize*0.1, textPos.Y + textSize*0.15, textSize * 0.7, textSize * 0.7}
+      lineThick := int32(textSize / 16)
+      if lineThick < 1 {
+        lineThick = 1
+      }
+      rl.DrawRectangleLinesEx(box, lineThick, rl.RayWhite)
+
+      if completion >= 1 {
+        inset := textSize * 0.15
+        rl.DrawRectangleRec(rl.Rectangle{box.X + inset, box.Y + inset, box.Width - inset*2, box.Height - inset*2}, rl.RayWhite)
+      }
 
-      size := float32(128.0)
+      textPos.X += textSize
 
-      //height, lineCount := TextHeight(text, guiMode)
+    }
 
-      pos.X = float32(int32(pos.X))
-      pos.Y = float32(int32(pos.Y))
+    if task.PrefixText != "" {
+      rl.DrawTextEx(not_shit_font, task.PrefixText, textPos, textSize, spacing, getThemeColor(GUI_OUTLINE_HIGHLIGHTED))
+      textPos.X += measureMarkdownText(task.PrefixText+" ", textSize).X
+    }
 
-      rl.DrawTextEx(not_shit_font, text, pos, size, spacing, rl.RayWhite)
+    task.NoteLayout().Draw(textPos, rl.RayWhite)
 
-      //for _, line := range strings.Split(text, "\n") {
-      //rl.DrawTextEx(not_shit_font, line, pos, size, spacing, fontColor)
-      //pos.Y += float32(int32(height / float32(lineCount)))
-      //}
+    // Progress is shown along the bottom of the Task, too; checkboxes without subtasks are either done or not, so
+    // they don't need it.
+    if hasCompletion && (task.Is(TASK_TYPE_PROGRESSION) || task.HasSubtaskCompletion()) {
+      bar := rl.Rectangle{task.Rect.X, task.Rect.Y + task.Rect.Height - 4, task.Rect.Width, 4}
+      rl.DrawRectangleRec(bar, getThemeColor(GUI_INSIDE))
+      bar.Width *= completion
+      rl.DrawRectangleRec(bar, getThemeColor(GUI_OUTLINE_HIGHLIGHTED))
     }
+
   }
 }
 
+// HasText returns whether the Task shows its description as text.
+func (task *Task) HasText() bool {
+  return task.Is(TASK_TYPE_NOTE, TASK_TYPE_CHECKBOX, TASK_TYPE_PROGRESSION)
+}
+
+func (task *Task) textSize() float32 {
+  if task.TextSize <= 0 {
+    return defaultNoteTextSize
+  }
+  return task.TextSize
+}
+
+// textIndent returns how far the Task's description is pushed over to make room for its checkbox and PrefixText.
+func (task *Task) textIndent() float32 {
+
+  indent := float32(0)
+
+  if task.Is(TASK_TYPE_CHECKBOX) {
+    indent += task.textSize()
+  }
+
+  if task.PrefixText != "" {
+    indent += measureMarkdownText(task.PrefixText+" ", task.textSize()).X
+  }
+
+  return indent
+
+}
+
+// NoteLayout returns the Task's description laid out as Markdown, wrapped to the Task's width if it has been
+// resized. The layout is kept until the text, width, or text size changes.
+func (task *Task) NoteLayout() *MarkdownLayout {
+
+  textSize := task.textSize()
+
+  wrapWidth := float32(0)
+  if task.DisplaySize.X >= task.MinSize.X {
+    wrapWidth = task.DisplaySize.X - 4 - task.textIndent()
+  }
+
+  if layout := task.textLayout; layout == nil || layout.text != task.Description || layout.width != wrapWidth || layout.fontSize != textSize {
+    task.textLayout = LayoutMarkdown(task.Description, textSize, wrapWidth)
+  }
+
+  return task.textLayout
+
+}
+
 func (task *Task) Depth() int {
 
   depth := 0
@@ -540,54 +573,233 @@ func (task *Task) Depth() int {
   return depth
 }
 
+// imageFileFilter lists the image formats raylib can load, for the image file picker.
+var imageFileFilter = zenity.FileFilter{Name: "Image File", Patterns: []string{
+  "*.png",
+  "*.bmp",
+  "*.jpeg",
+  "*.jpg",
+  "*.gif",
+  "*.dds",
+  "*.hdr",
+  "*.ktx",
+  "*.astc",
+}}
+
+// soundFileFilter lists the audio formats sound Tasks can play, for the sound file picker.
+var soundFileFilter = zenity.FileFilter{Name: "Sound File", Patterns: []string{
+  "*.wav",
+  "*.flac",
+  "*.ogg",
+  "*.oga",
+  "*.mp3",
+}}
+
+// PostDraw draws the Task's editor window if it's open; like the rest of the GUI, it has to be called between
+// imgui.NewFrame() and imgui.Render().
 func (task *Task) PostDraw() {
 
-  // NOTE(justasd): :Text
-  /*
-  if task.Open {
+  if !task.Open {
+    return
+  }
+
+  open := true
 
-    column := task.EditPanel.Columns[0]
+  imgui.SetNextWindowSizeV(imgui.Vec2{X: 480, Y: 360}, imgui.ConditionFirstUseEver)
 
-    column.Mode = task.TaskType
+  // The part after ### is the window's ID, so the title can change with the Task's type without the window moving.
+  if imgui.BeginV(fmt.Sprintf("Edit %s Task###Task%d", strings.Title(task.TaskType), task.ID), &open, 0) {
 
-    task.CreationLabel.Text = task.CreationTime.Format("Monday, Jan 2, 2006, 15:04")
+    if imgui.BeginCombo("Type", strings.Title(task.TaskType)) {
+      for _, taskType := range TaskTypes {
+        if imgui.SelectableV(strings.Title(taskType), task.TaskType == taskType, 0, imgui.Vec2{}) {
+          task.TaskType = taskType
+        }
+      }
+      imgui.EndCombo()
+    }
 
-    task.EditPanel.Update()
+    switch task.TaskType {
 
-    if task.LoadMediaButton.Clicked {
+    case TASK_TYPE_NOTE, TASK_TYPE_CHECKBOX, TASK_TYPE_PROGRESSION:
 
-      filepath := ""
-      var err error
+      if task.HasSubtaskCompletion() {
+        completion, _ := task.Completion()
+        imgui.Text(fmt.Sprintf("Subtasks are %d%% complete.", int(math.Round(float64(completion*100)))))
+      }
 
-      if task.Is(TASK_TYPE_IMAGE) {
+      if task.Is(TASK_TYPE_CHECKBOX) {
+        imgui.Checkbox("Completed", &task.Checked)
+      }
+
+      if task.Is(TASK_TYPE_PROGRESSION) {
+
+        current, max := int32(task.ProgressionCurrent), int32(task.ProgressionMax)
+
+        if imgui.InputInt("Current", &current) {
+          task.ProgressionCurrent = int(current)
+        }
+
+        if imgui.InputInt("Max", &max) {
+          task.ProgressionMax = int(max)
+        }
+
+        if task.ProgressionMax < 0 {
+          task.ProgressionMax = 0
+        }
+
+        if task.ProgressionCurrent < 0 {
+          task.ProgressionCurrent = 0
+        } else if task.ProgressionCurrent > task.ProgressionMax {
+          task.ProgressionCurrent = task.ProgressionMax
+        }
 
-        filepath, err = zenity.SelectFile(zenity.Title("Select image file"), zenity.FileFilters{zenity.FileFilter{Name: "Image File", Patterns: []string{
-          "*.png",
-          "*.bmp",
-          "*.jpeg",
-          "*.jpg",
-          "*.gif",
-          "*.dds",
-          "*.hdr",
-          "*.ktx",
-          "*.astc",
-        }}})
       }
 
-      if err == nil && filepath != "" {
-        task.FilePath.SetText(filepath)
+      textSize := task.textSize()
+
+      if imgui.SliderFloat("Text Size", &textSize, 8, 512) {
+        task.TextSize = textSize
+      }
+
+      imgui.Text("Description (Markdown)")
+      imgui.InputTextMultilineV("##Description", &task.Description, imgui.Vec2{X: -1, Y: -imgui.FrameHeightWithSpacing() * 2}, 0, nil)
+
+    case TASK_TYPE_SOUND:
+
+      imgui.InputText("File", &task.FilePath)
+
+      imgui.SameLine()
+
+      if imgui.Button("Browse...") {
+        if filePath, err := zenity.SelectFile(zenity.Title("Select sound file"), zenity.FileFilters{soundFileFilter}); err == nil && filePath != "" {
+          task.FilePath = filePath
+          task.LoadResource()
+        }
+      }
+
+      if task.drawResourceStateEditor() {
+        task.drawSoundEditor()
+      }
+
+    case TASK_TYPE_IMAGE:
+
+      imgui.InputText("File", &task.FilePath)
+
+      imgui.SameLine()
+
+      if imgui.Button("Browse...") {
+        if filePath, err := zenity.SelectFile(zenity.Title("Select image file"), zenity.FileFilters{imageFileFilter}); err == nil && filePath != "" {
+          task.FilePath = filePath
+        }
+      }
+
+      task.drawResourceStateEditor()
+
+      if task.Animation != nil {
+
+        label := "Pause"
+        if task.AnimationPaused {
+          label = "Play"
+        }
+
+        if imgui.Button(label) {
+          task.AnimationPaused = !task.AnimationPaused
+        }
+
+        imgui.SameLine()
+        imgui.Text(fmt.Sprintf("Frame %d / %d", task.Animation.FrameAt(task.animationTime)+1, len(task.Animation.Frames)))
+
+      }
+
+    }
+
+    task.drawConnectionEditor()
+
+    imgui.Separator()
+
+    imgui.Text("Created " + task.CreationTime.Format("Monday, Jan 2, 2006, 15:04"))
+
+    imgui.SameLine()
+
+    if imgui.Button("Done") {
+      open = false
+    }
+
+  }
+
+  imgui.End()
+
+  if !open {
+    task.ReceiveMessage(MessageTaskClose, nil)
+  }
+
+}
+
+// drawConnectionEditor lists the Task's Connections in its editor window, so they can be changed or removed.
+func (task *Task) drawConnectionEditor() {
+
+  connections := task.Board.ConnectionsOf(task)
+
+  if len(connections) == 0 || !imgui.CollapsingHeader(fmt.Sprintf("Connections (%d)", len(connections))) {
+    return
+  }
+
+  for i, connection := range connections {
+
+    imgui.PushID(fmt.Sprintf("Connection%d", i))
+
+    direction := "to"
+    if connection.End == task {
+      direction = "from"
+    }
+
+    imgui.Text(fmt.Sprintf("%s %s", strings.Title(direction), connectionLabel(connection.Other(task))))
+
+    changed := false
+
+    changed = imgui.InputText("Label", &connection.Label) || changed
+    changed = imgui.Checkbox("Arrow at Start", &connection.ArrowStart) || changed
+    imgui.SameLine()
+    changed = imgui.Checkbox("Arrow at End", &connection.ArrowEnd) || changed
+
+    if imgui.BeginCombo("Routing", strings.Title(connection.Routing)) {
+      for _, routing := range ConnectionRoutings {
+        if imgui.SelectableV(strings.Title(routing), connection.Routing == routing, 0, imgui.Vec2{}) {
+          connection.Routing = routing
+          changed = true
+        }
       }
+      imgui.EndCombo()
     }
 
-    if task.EditPanel.Exited {
-      task.ReceiveMessage(MessageTaskClose, nil)
+    color := connection.DrawColor()
+    colorValues := [4]float32{float32(color.R) / 255, float32(color.G) / 255, float32(color.B) / 255, float32(color.A) / 255}
+
+    if imgui.ColorEdit4("Color", &colorValues) {
+      connection.Color = rl.Color{uint8(colorValues[0] * 255), uint8(colorValues[1] * 255), uint8(colorValues[2] * 255), uint8(colorValues[3] * 255)}
+      changed = true
     }
+
+    if imgui.Button("Remove Connection") {
+      task.Board.Disconnect(connection)
+    }
+
+    if changed {
+      task.Board.Project.UndoHistory.CaptureConnection(connection)
+      task.Board.Project.MarkModified()
+    }
+
+    imgui.Separator()
+
+    imgui.PopID()
+
   }
-  */
+
 }
 
 func (task *Task) Resizeable() bool {
-  return task.Is(TASK_TYPE_IMAGE, TASK_TYPE_NOTE)
+  return task.Is(TASK_TYPE_IMAGE, TASK_TYPE_SOUND) || task.HasText()
 }
 
 func (task *Task) LoadResource() {
@@ -598,25 +810,72 @@ func (task *Task) LoadResource() {
 
     res, _ := task.Board.Project.LoadResource(task.FilePath)
 
-    if res != nil {
+    if res != nil && res.State != RESOURCE_STATE_LOADED {
+
+      // The Resource is loaded in the background, and we'll be told to load it again once it's ready. Until then,
+      // whatever the Task was showing or playing before is let go of if it was for another file.
+      if task.PrevFilePath != task.FilePath {
+
+        task.Image = rl.Texture2D{}
+        task.Animation = nil
+
+        if task.Sound != nil {
+          task.Sound.Close()
+          task.Sound = nil
+        }
+
+      }
+
+    } else if res != nil {
 
       task.SuccessfullyLoadedResourceOnce = true
 
       if task.Is(TASK_TYPE_IMAGE) {
 
-        if res.IsTexture() {
+        task.Animation = nil
 
+        if res.IsTexture() {
           task.Image = res.Texture()
-          if task.PrevFilePath != task.FilePath && task.DisplaySize.X == 0 && task.DisplaySize.Y == 0 {
-            task.DisplaySize.X = float32(task.Image.Width)
-            task.DisplaySize.Y = float32(task.Image.Height)
-          }
+        } else if res.IsGif() && len(res.Gif().Frames) > 0 {
+          task.Animation = res.Gif()
+          task.Image = task.Animation.Frames[task.Animation.FrameAt(task.animationTime)]
+        }
+
+        if task.Image.ID > 0 && task.PrevFilePath != task.FilePath && task.DisplaySize.X == 0 && task.DisplaySize.Y == 0 {
+          task.DisplaySize.X = float32(task.Image.Width)
+          task.DisplaySize.Y = float32(task.Image.Height)
+        }
+      }
+
+      if task.Is(TASK_TYPE_SOUND) && !headless && res.IsAudio() && (task.Sound == nil || task.PrevFilePath != task.FilePath) {
+
+        if task.Sound != nil {
+          task.Sound.Close()
+          task.Sound = nil
         }
+
+        if player, err := NewSoundPlayer(res); err == nil {
+          task.Sound = player
+        } else {
+          task.Board.Project.Log("Could not play sound [%s]: %s", task.FilePath, err.Error())
+        }
+
       }
 
       task.PrevFilePath = task.FilePath
     }
   }
+
+  if task.Is(TASK_TYPE_SOUND) && task.DisplaySize.X == 0 && task.DisplaySize.Y == 0 {
+    gs := float32(task.Board.Project.GridSize)
+    task.DisplaySize = rl.Vector2{gs * 16, gs * 4}
+  }
+
+  // A Task that's been changed to another type doesn't need its sound anymore.
+  if !task.Is(TASK_TYPE_SOUND) && task.Sound != nil {
+    task.Sound.Close()
+    task.Sound = nil
+  }
 }
 
 func (task *Task) ReceiveMessage(message string, data map[string]interface{}) {
@@ -651,6 +910,7 @@ func (task *Task) ReceiveMessage(message string, data map[string]interface{}) {
       task.Board.Project.TaskOpen = false
       task.LoadResource()
       task.Board.Project.PreviousTaskType = task.TaskType
+      task.Board.Project.UndoHistory.Capture(task)
 
       // We call ReorderTasks here because changing the Task can change its Rect,
       // thereby changing its neighbors.
@@ -660,7 +920,7 @@ func (task *Task) ReceiveMessage(message string, data map[string]interface{}) {
   } else if message == MessageDragging {
     if task.Selected {
       task.Dragging = true
-      task.MouseDragStart = GetWorldMousePosition()
+      task.MouseDragStart = rlVector(task.Board.Project.Viewport.WorldMousePosition())
       task.TaskDragStart = task.Position
     }
   } else if message == MessageDropped {
@@ -670,12 +930,17 @@ func (task *Task) ReceiveMessage(message string, data map[string]interface{}) {
     task.Position = task.Board.Project.LockPositionToGrid(task.Position)
     task.Board.RemoveTaskFromGrid(task)
     task.Board.AddTaskToGrid(task)
+    task.Board.Project.UndoHistory.Capture(task)
   } else if message == MessageDelete {
 
     // We remove the Task from the grid but not change the GridPositions list because undos need to
     // re-place the Task at the original position.
     task.Board.RemoveTaskFromGrid(task)
 
+    if task.Sound != nil {
+      task.Sound.Pause()
+    }
+
   } else if message == MessageThemeChange {
   } else {
     fmt.Println("UNKNOWN MESSAGE: ", message)
@@ -724,10 +989,14 @@ func (task *Task) Move(dx, dy float32) {
 }
 
 func (task *Task) Destroy() {
+  if task.Sound != nil {
+    task.Sound.Close()
+    task.Sound = nil
+  }
 }
 
 func (task *Task) UsesMedia() bool {
-  return task.Is(TASK_TYPE_IMAGE)
+  return task.Is(TASK_TYPE_IMAGE, TASK_TYPE_SOUND)
 }
 
 func (task *Task) Is(taskTypes ...string) bool {
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"github.com/solarlune/masterplan/model"
+	"github.com/tidwall/sjson"
+)
+
+// UndoTaskState holds a Task's serialized state from before and after a change. An empty string
+// means the Task didn't exist on a Board at that point (i.e. it was created or deleted in the change).
+type UndoTaskState struct {
+	Task   *Task
+	Before string
+	After  string
+}
+
+// UndoBoardState records a Board being added to, removed from or moved within the Project's Board stack. From and To
+// are the Board's index before and after the change, with -1 meaning it wasn't in the stack.
+type UndoBoardState struct {
+	Board *Board
+	From  int
+	To    int
+}
+
+// UndoBoardRename records a Board being renamed.
+type UndoBoardRename struct {
+	Board  *Board
+	Before string
+	After  string
+}
+
+// UndoConnectionState holds a Connection's serialized state from before and after a change, the same way as
+// UndoTaskState; an empty string means the Connection wasn't on its Board.
+type UndoConnectionState struct {
+	Connection *Connection
+	Before     string
+	After      string
+}
+
+// UndoFrame is a single step in the undo history; everything that changed within one frame
+// of the program gets undone and redone together.
+type UndoFrame struct {
+	Boards      []UndoBoardState
+	Renames     []UndoBoardRename
+	Tasks       []UndoTaskState
+	Connections []UndoConnectionState
+}
+
+type UndoHistory struct {
+	Project *Project
+	Frames  []*UndoFrame
+	// Index is the number of Frames that are currently applied; Frames past it can be redone.
+	Index int
+
+	touched            []*Task
+	touchedConnections []*Connection
+	boards             []UndoBoardState
+	renames            []UndoBoardRename
+	states             map[*Task]string
+	connectionStates   map[*Connection]string
+}
+
+func NewUndoHistory(project *Project) *UndoHistory {
+	history := &UndoHistory{Project: project}
+	history.Reset()
+	return history
+}
+
+// Reset clears the history and takes the current state of every Task as the baseline to record changes against.
+func (history *UndoHistory) Reset() {
+
+	history.Frames = []*UndoFrame{}
+	history.Index = 0
+	history.touched = []*Task{}
+	history.touchedConnections = []*Connection{}
+	history.boards = []UndoBoardState{}
+	history.renames = []UndoBoardRename{}
+	history.states = map[*Task]string{}
+	history.connectionStates = map[*Connection]string{}
+
+	for _, task := range history.Project.GetAllTasks() {
+		history.states[task] = undoState(task)
+	}
+
+	for _, board := range history.Project.Boards {
+		for _, connection := range board.Connections {
+			history.connectionStates[connection] = connectionUndoState(connection)
+		}
+	}
+
+}
+
+func (history *UndoHistory) recording() bool {
+	return history.Project.Undoing == 0 && !history.Project.JustLoaded
+}
+
+// Capture marks the Task as changed; its new state gets recorded at the end of the frame, so it's fine to
+// capture a Task before altering it further.
+func (history *UndoHistory) Capture(task *Task) {
+
+	if !history.recording() {
+		return
+	}
+
+	for _, t := range history.touched {
+		if t == task {
+			return
+		}
+	}
+
+	history.touched = append(history.touched, task)
+
+}
+
+// CaptureConnection marks the Connection as changed (including being added to or removed from its Board), the
+// same way as Capture() does for Tasks.
+func (history *UndoHistory) CaptureConnection(connection *Connection) {
+
+	if !history.recording() {
+		return
+	}
+
+	for _, c := range history.touchedConnections {
+		if c == connection {
+			return
+		}
+	}
+
+	history.touchedConnections = append(history.touchedConnections, connection)
+
+}
+
+// CaptureBoard records the Board as having moved from one index in the Project's Board stack to another; an index
+// of -1 means it was added (for from) or removed (for to).
+func (history *UndoHistory) CaptureBoard(board *Board, from, to int) {
+
+	if !history.recording() || from == to {
+		return
+	}
+
+	history.boards = append(history.boards, UndoBoardState{Board: board, From: from, To: to})
+
+}
+
+// CaptureBoardRename records the Board as having been renamed from one name to another.
+func (history *UndoHistory) CaptureBoardRename(board *Board, before, after string) {
+
+	if !history.recording() || before == after {
+		return
+	}
+
+	history.renames = append(history.renames, UndoBoardRename{Board: board, Before: before, After: after})
+
+}
+
+// Update commits everything captured in this frame as a new undo step. It should be called once per frame after
+// the Boards have handled their deleted and restored Tasks.
+func (history *UndoHistory) Update() {
+
+	if len(history.touched) == 0 && len(history.touchedConnections) == 0 && len(history.boards) == 0 && len(history.renames) == 0 {
+		return
+	}
+
+	frame := &UndoFrame{Boards: history.boards, Renames: history.renames}
+
+	for _, task := range history.touched {
+
+		after := ""
+		if history.taskExists(task) {
+			after = undoState(task)
+		}
+
+		before := history.states[task]
+
+		if before != after {
+			frame.Tasks = append(frame.Tasks, UndoTaskState{Task: task, Before: before, After: after})
+			history.states[task] = after
+		}
+
+	}
+
+	for _, connection := range history.touchedConnections {
+
+		after := ""
+		if history.connectionExists(connection) {
+			after = connectionUndoState(connection)
+		}
+
+		before := history.connectionStates[connection]
+
+		if before != after {
+			frame.Connections = append(frame.Connections, UndoConnectionState{Connection: connection, Before: before, After: after})
+			history.connectionStates[connection] = after
+		}
+
+	}
+
+	history.touched = []*Task{}
+	history.touchedConnections = []*Connection{}
+	history.boards = []UndoBoardState{}
+	history.renames = []UndoBoardRename{}
+
+	if len(frame.Tasks) == 0 && len(frame.Connections) == 0 && len(frame.Boards) == 0 && len(frame.Renames) == 0 {
+		return
+	}
+
+	// Making a new change throws away anything that could have been redone.
+	history.Frames = append(history.Frames[:history.Index], frame)
+
+	if maxSteps := programSettings.MaxUndoSteps; maxSteps > 0 && len(history.Frames) > maxSteps {
+		history.Frames = history.Frames[len(history.Frames)-maxSteps:]
+	}
+
+	history.Index = len(history.Frames)
+
+	history.Project.MarkModified()
+
+}
+
+func (history *UndoHistory) Undo() bool {
+
+	if history.Index == 0 {
+		return false
+	}
+
+	history.Index--
+	frame := history.Frames[history.Index]
+
+	history.Project.Undoing++
+
+	// Boards are put back before their Tasks are touched, and only taken out afterward, so Tasks are never changed
+	// while their Board's out of the Project.
+	for i := len(frame.Boards) - 1; i >= 0; i-- {
+		if bs := frame.Boards[i]; bs.From >= 0 {
+			history.Project.placeBoard(bs.Board, bs.From)
+		}
+	}
+
+	for i := len(frame.Renames) - 1; i >= 0; i-- {
+		frame.Renames[i].Board.Name = frame.Renames[i].Before
+	}
+
+	changed := []*Task{}
+	for i := len(frame.Tasks) - 1; i >= 0; i-- {
+		ts := frame.Tasks[i]
+		history.apply(ts.Task, ts.Before)
+		if ts.Before != "" {
+			changed = append(changed, ts.Task)
+		}
+	}
+
+	for i := len(frame.Connections) - 1; i >= 0; i-- {
+		cs := frame.Connections[i]
+		history.applyConnection(cs.Connection, cs.Before)
+	}
+
+	for i := len(frame.Boards) - 1; i >= 0; i-- {
+		if bs := frame.Boards[i]; bs.From < 0 {
+			history.Project.detachBoard(bs.Board)
+		}
+	}
+
+	history.Project.Undoing--
+
+	history.Project.MarkModified()
+	history.selectChanged(changed)
+	history.Project.Log("Undid step %d / %d.", history.Index+1, len(history.Frames))
+
+	return true
+
+}
+
+func (history *UndoHistory) Redo() bool {
+
+	if history.Index >= len(history.Frames) {
+		return false
+	}
+
+	frame := history.Frames[history.Index]
+	history.Index++
+
+	history.Project.Undoing++
+
+	for _, bs := range frame.Boards {
+		if bs.To >= 0 {
+			history.Project.placeBoard(bs.Board, bs.To)
+		}
+	}
+
+	for _, rename := range frame.Renames {
+		rename.Board.Name = rename.After
+	}
+
+	changed := []*Task{}
+	for _, ts := range frame.Tasks {
+		history.apply(ts.Task, ts.After)
+		if ts.After != "" {
+			changed = append(changed, ts.Task)
+		}
+	}
+
+	for _, cs := range frame.Connections {
+		history.applyConnection(cs.Connection, cs.After)
+	}
+
+	for _, bs := range frame.Boards {
+		if bs.To < 0 {
+			history.Project.detachBoard(bs.Board)
+		}
+	}
+
+	history.Project.Undoing--
+
+	history.Project.MarkModified()
+	history.selectChanged(changed)
+	history.Project.Log("Redid step %d / %d.", history.Index, len(history.Frames))
+
+	return true
+
+}
+
+// apply sets the Task to the given serialized state, deleting it if the state is empty and restoring it if it
+// didn't exist before.
+func (history *UndoHistory) apply(task *Task, state string) {
+
+	existed := history.states[task] != ""
+
+	if state == "" {
+		if existed {
+			task.Board.DeleteTask(task)
+		}
+	} else {
+
+		task.Deserialize(state)
+
+		if existed {
+			task.ReceiveMessage(MessageDropped, nil) // Re-place the Task in the Board's grid
+		} else {
+
+			// The ID may have been handed out to a new Task while this one was deleted.
+			for _, other := range history.Project.GetAllTasks() {
+				if other != task && other.ID == task.ID {
+					task.ID = history.Project.FirstFreeID()
+					break
+				}
+			}
+
+			task.Board.RestoreTask(task)
+
+		}
+
+	}
+
+	history.states[task] = state
+
+}
+
+// applyConnection sets the Connection to the given serialized state, taking it off of its Board if the state is
+// empty and putting it back if it wasn't there.
+func (history *UndoHistory) applyConnection(connection *Connection, state string) {
+
+	board := connection.Start.Board
+
+	if state == "" {
+		board.Disconnect(connection)
+	} else {
+
+		connection.applyData(model.ParseConnectionData(state))
+
+		if !history.connectionExists(connection) {
+			board.Connections = append(board.Connections, connection)
+		}
+
+		board.Project.MarkModified()
+
+	}
+
+	history.connectionStates[connection] = state
+
+}
+
+func (history *UndoHistory) selectChanged(changed []*Task) {
+
+	if len(changed) == 0 {
+		return
+	}
+
+	history.Project.SendMessage(MessageSelect, nil)
+
+	for _, task := range changed {
+		task.Selected = true
+	}
+
+}
+
+func (history *UndoHistory) taskExists(task *Task) bool {
+
+	if task.Board.Index() < 0 {
+		return false
+	}
+
+	for _, t := range task.Board.Tasks {
+		if t == task {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+func (history *UndoHistory) connectionExists(connection *Connection) bool {
+
+	for _, c := range connection.Start.Board.Connections {
+		if c == connection {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// undoState returns the Task's serialized state for the undo history; selection isn't something that should
+// be undone, so it's left out.
+func undoState(task *Task) string {
+	state, _ := sjson.Delete(task.Serialize(), `Selected`)
+	return state
+}
+
+// FilePaths returns the files used by the Tasks in the undo history, as they are and in every state they've been in,
+// as undoing or redoing could bring any of them back. That includes the Tasks on Boards that have been deleted.
+func (history *UndoHistory) FilePaths() []string {
+
+	paths := []string{}
+
+	addTask := func(task *Task) {
+		if task.UsesMedia() && task.FilePath != "" {
+			paths = append(paths, task.FilePath)
+		}
+	}
+
+	addState := func(state string) {
+		if state != "" {
+			if data := model.ParseTaskData(state, history.Project.FilePath); data.UsesMedia() && data.FilePath != "" {
+				paths = append(paths, data.FilePath)
+			}
+		}
+	}
+
+	for _, frame := range history.Frames {
+
+		for _, taskState := range frame.Tasks {
+			addTask(taskState.Task)
+			addState(taskState.Before)
+			addState(taskState.After)
+		}
+
+		for _, boardState := range frame.Boards {
+			for _, task := range boardState.Board.Tasks {
+				addTask(task)
+			}
+		}
+
+	}
+
+	for task, state := range history.states {
+		addTask(task)
+		addState(state)
+	}
+
+	return paths
+
+}
+
+// connectionUndoState returns the Connection's serialized state for the undo history. Its Tasks are left out, as
+// they never change, and their IDs can (when a restored Task's ID has been taken in the meantime).
+func connectionUndoState(connection *Connection) string {
+	state, _ := sjson.Delete(connection.Serialize(), `Start`)
+	state, _ = sjson.Delete(state, `End`)
+	return state
+}
@@ -0,0 +1,115 @@
+// Package unzip extracts zip archives that can't be trusted, like bundles passed around between people, without
+// letting them write anywhere outside of where they're extracted to.
+package unzip
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Extract extracts the zip archive into the destination directory. Archives with entries that would end up outside
+// of the destination (like "../../.bashrc", or an absolute path), or with symbolic links, which could point anywhere,
+// return an error; nothing's extracted from them at all.
+func Extract(archivePath, destination string) error {
+
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+
+	defer archive.Close()
+
+	destination, err = filepath.Abs(destination)
+	if err != nil {
+		return err
+	}
+
+	// Every entry's checked before anything's written, so a bad archive doesn't leave half of itself behind.
+	targets := make([]string, len(archive.File))
+
+	for i, file := range archive.File {
+
+		if file.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("archive entry [%s] is a symbolic link", file.Name)
+		}
+
+		target, err := entryPath(destination, file.Name)
+		if err != nil {
+			return err
+		}
+
+		targets[i] = target
+
+	}
+
+	for i, file := range archive.File {
+
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(targets[i], 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := extractFile(file, targets[i]); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+// entryPath returns where the archive entry's extracted to in the destination, or an error if that's outside of it.
+func entryPath(destination, name string) (string, error) {
+
+	// Zip entries always use forward slashes, but archives made on Windows sometimes have backslashes anyway.
+	name = strings.Replace(name, `\`, "/", -1)
+
+	if name == "" || strings.HasPrefix(name, "/") || filepath.VolumeName(filepath.FromSlash(name)) != "" {
+		return "", fmt.Errorf("archive entry [%s] has an absolute path", name)
+	}
+
+	target := filepath.Join(destination, filepath.FromSlash(name))
+
+	relative, err := filepath.Rel(destination, target)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry [%s] is outside of the directory it's extracted to", name)
+	}
+
+	return target, nil
+
+}
+
+func extractFile(file *zip.File, target string) error {
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	// Only the permission bits are kept from the archive; it doesn't get to make files setuid, for example.
+	output, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode().Perm()|0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(output, reader); err != nil {
+		output.Close()
+		return err
+	}
+
+	return output.Close()
+
+}
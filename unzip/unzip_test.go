@@ -0,0 +1,144 @@
+package unzip
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testEntry struct {
+	Name string
+	Body string
+	Mode os.FileMode
+}
+
+// writeArchive writes a zip archive of the entries into the directory, returning its path.
+func writeArchive(t *testing.T, directory string, entries []testEntry) string {
+
+	archivePath := filepath.Join(directory, "test.zip")
+
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+
+	for _, entry := range entries {
+
+		header := &zip.FileHeader{Name: entry.Name, Method: zip.Deflate}
+		if entry.Mode != 0 {
+			header.SetMode(entry.Mode)
+		}
+
+		w, err := writer.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write([]byte(entry.Body)); err != nil {
+			t.Fatal(err)
+		}
+
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return archivePath
+
+}
+
+func newTestDirectory(t *testing.T) (string, func()) {
+
+	directory, err := ioutil.TempDir("", "unzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return directory, func() { os.RemoveAll(directory) }
+
+}
+
+func TestExtract(t *testing.T) {
+
+	directory, cleanup := newTestDirectory(t)
+	defer cleanup()
+
+	archivePath := writeArchive(t, directory, []testEntry{
+		{Name: "project.plan", Body: `{"Tasks": []}`},
+		{Name: "resources/", Mode: os.ModeDir | 0755},
+		{Name: "resources/cat.png", Body: "meow"},
+	})
+
+	extractPath := filepath.Join(directory, "extracted")
+
+	if err := Extract(archivePath, extractPath); err != nil {
+		t.Fatalf("Extract() returned an error: %s", err)
+	}
+
+	for name, want := range map[string]string{"project.plan": `{"Tasks": []}`, "resources/cat.png": "meow"} {
+		if data, err := ioutil.ReadFile(filepath.Join(extractPath, filepath.FromSlash(name))); err != nil || string(data) != want {
+			t.Errorf("got %q (%v) for %s, want %q", data, err, name, want)
+		}
+	}
+
+}
+
+func TestExtractRejectsEscapingEntries(t *testing.T) {
+
+	for _, name := range []string{"../evil.txt", "resources/../../evil.txt", `..\evil.txt`, "/tmp/evil.txt"} {
+
+		directory, cleanup := newTestDirectory(t)
+
+		archivePath := writeArchive(t, directory, []testEntry{
+			{Name: "project.plan", Body: `{"Tasks": []}`},
+			{Name: name, Body: "gotcha"},
+		})
+
+		extractPath := filepath.Join(directory, "bundles", "extracted")
+
+		if err := Extract(archivePath, extractPath); err == nil {
+			t.Errorf("extracting an entry named [%s] didn't return an error", name)
+		}
+
+		// Nothing's extracted from a bad archive, not even its good entries.
+		if _, err := os.Stat(filepath.Join(extractPath, "project.plan")); err == nil {
+			t.Errorf("an archive with an entry named [%s] was partly extracted", name)
+		}
+
+		if _, err := os.Stat(filepath.Join(directory, "bundles", "evil.txt")); err == nil {
+			t.Errorf("an entry named [%s] was extracted outside of the directory", name)
+		}
+
+		cleanup()
+
+	}
+
+}
+
+func TestExtractRejectsSymlinks(t *testing.T) {
+
+	directory, cleanup := newTestDirectory(t)
+	defer cleanup()
+
+	archivePath := writeArchive(t, directory, []testEntry{
+		{Name: "resources/cat.png", Body: "/etc/passwd", Mode: os.ModeSymlink | 0777},
+	})
+
+	extractPath := filepath.Join(directory, "extracted")
+
+	if err := Extract(archivePath, extractPath); err == nil {
+		t.Errorf("extracting a symbolic link didn't return an error")
+	}
+
+	if _, err := os.Lstat(filepath.Join(extractPath, "resources", "cat.png")); err == nil {
+		t.Errorf("the symbolic link was extracted")
+	}
+
+}
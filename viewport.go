@@ -0,0 +1,78 @@
+package main
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/solarlune/masterplan/model"
+)
+
+// headless is set when running without a window (e.g. from the command line); there's no GPU to upload
+// textures to and nothing to play sounds with.
+var headless = false
+
+// windowViewport is the Viewport of the raylib window, looking through the global camera.
+type windowViewport struct{}
+
+func (viewport windowViewport) WorldMousePosition() model.Vector {
+	return modelVector(GetWorldMousePosition())
+}
+
+func (viewport windowViewport) Center() model.Vector {
+	return modelVector(camera.Target)
+}
+
+func (viewport windowViewport) VisibleRect() model.Rect {
+	w := float32(rl.GetScreenWidth()) / camera.Zoom
+	h := float32(rl.GetScreenHeight()) / camera.Zoom
+	return model.Rect{camera.Target.X - (w / 2), camera.Target.Y - (h / 2), w, h}
+}
+
+// windowInput reads input from the raylib window, through the program's mouse input handling and keybindings.
+type windowInput struct{}
+
+func (input windowInput) MousePressed(button int32) bool {
+	return MousePressed(button)
+}
+
+func (input windowInput) MouseDown(button int32) bool {
+	return MouseDown(button)
+}
+
+func (input windowInput) MouseReleased(button int32) bool {
+	return MouseReleased(button)
+}
+
+func (input windowInput) BindingOn(bindingName string) bool {
+	return programSettings.Keybindings.On(bindingName)
+}
+
+func (input windowInput) DroppedFiles() []string {
+
+	if !rl.IsFileDropped() {
+		return nil
+	}
+
+	fileCount := int32(0)
+	files := append([]string{}, rl.GetDroppedFiles(&fileCount)...)
+	rl.ClearDroppedFiles()
+
+	return files
+
+}
+
+// The model has its own vector and rectangle types, as it doesn't depend on raylib; these convert between them.
+
+func modelVector(v rl.Vector2) model.Vector {
+	return model.Vector{v.X, v.Y}
+}
+
+func modelRect(r rl.Rectangle) model.Rect {
+	return model.Rect{r.X, r.Y, r.Width, r.Height}
+}
+
+func rlVector(v model.Vector) rl.Vector2 {
+	return rl.Vector2{v.X, v.Y}
+}
+
+func rlRect(r model.Rect) rl.Rectangle {
+	return rl.Rectangle{r.X, r.Y, r.Width, r.Height}
+}